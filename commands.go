@@ -0,0 +1,378 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/tedfulk/goatmeal/config"
+	"github.com/tedfulk/goatmeal/database"
+	"github.com/tedfulk/goatmeal/services/backup"
+	"github.com/tedfulk/goatmeal/services/importer"
+)
+
+const usage = `Usage: goatmeal [command]
+
+Without a command goatmeal starts the chat.
+
+Commands:
+  cleanup [--dry-run] [--days N] [--archive]  Delete conversations not updated in the retention period
+  import <file>...                           Import ChatGPT, Claude.ai or goatmeal exports
+  backup [--include-keys] <file>             Back up conversations, config and archives to one file
+  restore [--config] <file>                  Merge a backup into this machine's conversations
+  encrypt                                    Encrypt stored conversations with a passphrase
+  decrypt                                    Store conversations unencrypted again
+  rekey                                      Change the passphrase of encrypted conversations
+`
+
+// runCommand runs a command given on the command line and returns the exit code
+func runCommand(cfg *config.Config, args []string) int {
+	switch args[0] {
+	case "cleanup":
+		return cleanupCommand(cfg, args[1:])
+	case "import":
+		return importCommand(cfg, args[1:])
+	case "backup":
+		return backupCommand(cfg, args[1:])
+	case "restore":
+		return restoreCommand(cfg, args[1:])
+	case "encrypt":
+		return encryptCommand(cfg)
+	case "decrypt":
+		return decryptCommand(cfg)
+	case "rekey":
+		return rekeyCommand(cfg)
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n%s", args[0], usage)
+		return 2
+	}
+}
+
+// cleanupCommand deletes or archives expired conversations, or lists them with --dry-run
+func cleanupCommand(cfg *config.Config, args []string) int {
+	flags := flag.NewFlagSet("cleanup", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "list the conversations that would be deleted without deleting them")
+	days := flags.Int("days", cfg.Settings.ConversationRetention, "retention period in days")
+	archive := flags.Bool("archive", cfg.Settings.ArchiveExpired, "archive conversations to compressed JSONL before deleting them")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *days <= 0 {
+		fmt.Println("Retention is disabled, no conversations are deleted")
+		return 0
+	}
+
+	archiveDir, err := retentionArchiveDir(*archive)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error finding the archive directory: %v\n", err)
+		return 1
+	}
+
+	db, err := openDB(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error initializing database: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	if archiveDir != "" && db.Encrypted() {
+		fmt.Println("Conversations are encrypted, so they are deleted without writing an unencrypted archive")
+		archiveDir = ""
+	}
+
+	expired, err := db.ExpiredConversations(*days)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error finding old conversations: %v\n", err)
+		return 1
+	}
+	if len(expired) == 0 {
+		fmt.Printf("Nothing to clean up, every conversation was updated in the last %d days or is pinned\n", *days)
+		return 0
+	}
+
+	if !*dryRun {
+		deleted, err := db.CleanupOldConversations(*days, archiveDir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error cleaning up old conversations: %v\n", err)
+			return 1
+		}
+		if archiveDir != "" {
+			fmt.Printf("Deleted %d conversations, archiving the ones not in the trash to %s\n", deleted, archiveDir)
+		} else {
+			fmt.Printf("Deleted %d conversations\n", deleted)
+		}
+		return 0
+	}
+
+	action := "delete"
+	if archiveDir != "" {
+		action = "archive"
+	}
+	fmt.Printf("Cleanup would %s these conversations, not updated or in the trash for %d days:\n\n", action, *days)
+	for _, conv := range expired {
+		if conv.DeletedAt.IsZero() {
+			fmt.Printf("  %s  %s\n", conv.UpdatedAt.Format("2006-01-02"), conv.Title)
+		} else {
+			fmt.Printf("  %s  %s (in trash, deleted)\n", conv.DeletedAt.Format("2006-01-02"), conv.Title)
+		}
+	}
+	fmt.Printf("\n%d conversations. Pin a conversation to keep it\n", len(expired))
+	return 0
+}
+
+// retentionArchiveDir returns the directory expired conversations are archived to,
+// or "" when archive is false and they are deleted without one
+func retentionArchiveDir(archive bool) (string, error) {
+	if !archive {
+		return "", nil
+	}
+	dir, err := config.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "archive"), nil
+}
+
+// importCommand imports the conversations in each export file
+func importCommand(cfg *config.Config, files []string) int {
+	if len(files) == 0 {
+		fmt.Fprint(os.Stderr, "Usage: goatmeal import <file>...\n")
+		return 2
+	}
+
+	db, err := openDB(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error initializing database: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	code := 0
+	for _, file := range files {
+		result, err := importer.ImportFile(db, file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error importing %s: %v\n", file, err)
+			code = 1
+			continue
+		}
+		fmt.Printf("%s: %s\n", file, result)
+	}
+	return code
+}
+
+// backupCommand writes the database, config and retention archives to a backup file
+func backupCommand(cfg *config.Config, args []string) int {
+	flags := flag.NewFlagSet("backup", flag.ContinueOnError)
+	includeKeys := flags.Bool("include-keys", false, "keep the API keys in the backed up config")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprint(os.Stderr, "Usage: goatmeal backup [--include-keys] <file>\n")
+		return 2
+	}
+	file := flags.Arg(0)
+
+	configDir, err := config.Dir()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error finding the config directory: %v\n", err)
+		return 1
+	}
+
+	db, err := openDB(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error initializing database: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	manifest, err := backup.Create(db, configDir, file, backup.Options{IncludeKeys: *includeKeys})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error backing up: %v\n", err)
+		return 1
+	}
+
+	fmt.Print("Backed up conversations")
+	if manifest.HasConfig {
+		fmt.Print(", config")
+	}
+	if manifest.Archives > 0 {
+		fmt.Printf(" and %d archives", manifest.Archives)
+	}
+	fmt.Printf(" to %s\n", file)
+	if manifest.HasConfig && manifest.KeysRedacted {
+		fmt.Println("API keys were left out, use --include-keys to keep them")
+	}
+	return 0
+}
+
+// restoreCommand merges a backup into the database and restores its config and archives
+func restoreCommand(cfg *config.Config, args []string) int {
+	flags := flag.NewFlagSet("restore", flag.ContinueOnError)
+	replaceConfig := flags.Bool("config", false, "replace the existing config with the backed up one")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprint(os.Stderr, "Usage: goatmeal restore [--config] <file>\n")
+		return 2
+	}
+
+	configDir, err := config.Dir()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error finding the config directory: %v\n", err)
+		return 1
+	}
+
+	db, err := openDB(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error initializing database: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	opts := backup.RestoreOptions{
+		ReplaceConfig: *replaceConfig,
+		// An encrypted backup usually has the same passphrase, it's asked for when it doesn't
+		Unlock: func(backup *database.DB) error {
+			return unlock(backup, cfg, "Backup passphrase", true)
+		},
+	}
+	result, err := backup.Restore(db, configDir, flags.Arg(0), opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error restoring: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("Restored %d conversations, added %d messages to %d existing ones, %d were up to date\n",
+		result.Added, result.Messages, result.Updated, result.Unchanged)
+	if result.Archives > 0 {
+		fmt.Printf("Restored %d archives\n", result.Archives)
+	}
+	switch result.Config {
+	case backup.ConfigRestored:
+		fmt.Println("Restored config.yaml")
+	case backup.ConfigKept:
+		fmt.Println("Kept the existing config.yaml, use --config to replace it")
+	case backup.ConfigReplaced:
+		fmt.Printf("Replaced config.yaml, the previous one was moved to %s\n", result.ConfigBackup)
+	}
+	if result.Config == backup.ConfigRestored && result.Manifest.KeysRedacted {
+		fmt.Println("The backup has no API keys, add them in the settings")
+	}
+	return 0
+}
+
+// encryptCommand encrypts the stored conversations with a new passphrase
+func encryptCommand(cfg *config.Config) int {
+	db, err := openDB(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error initializing database: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	if db.Encrypted() {
+		fmt.Fprint(os.Stderr, "Conversations are already encrypted, use rekey to change the passphrase\n")
+		return 1
+	}
+	passphrase, err := newPassphrase()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if err := db.EnableEncryption(passphrase); err != nil {
+		fmt.Fprintf(os.Stderr, "Error encrypting conversations: %v\n", err)
+		return 1
+	}
+
+	fmt.Println("Encrypted conversations. They can't be recovered without the passphrase, keep it somewhere safe")
+	if backups, _ := filepath.Glob(dbPath() + ".v*.bak"); len(backups) > 0 {
+		fmt.Println("These backups made before upgrades are not encrypted, delete them if you don't need them:")
+		for _, b := range backups {
+			fmt.Printf("  %s\n", b)
+		}
+	}
+	// Retention no longer archives, but the archives written so far hold the conversations in plain text
+	if archiveDir, err := retentionArchiveDir(true); err == nil {
+		if archives, _ := filepath.Glob(filepath.Join(archiveDir, "*.jsonl.gz")); len(archives) > 0 {
+			fmt.Println("These retention archives are not encrypted, delete them if you don't need them:")
+			for _, a := range archives {
+				fmt.Printf("  %s\n", a)
+			}
+		}
+	}
+	return 0
+}
+
+// decryptCommand stores the conversations unencrypted again
+func decryptCommand(cfg *config.Config) int {
+	db, err := openDB(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error initializing database: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	if !db.Encrypted() {
+		fmt.Fprint(os.Stderr, "Conversations are not encrypted\n")
+		return 1
+	}
+	if err := db.DisableEncryption(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error decrypting conversations: %v\n", err)
+		return 1
+	}
+	fmt.Println("Decrypted conversations")
+	return 0
+}
+
+// rekeyCommand encrypts the conversations again with a new passphrase
+func rekeyCommand(cfg *config.Config) int {
+	db, err := openDB(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error initializing database: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	if !db.Encrypted() {
+		fmt.Fprint(os.Stderr, "Conversations are not encrypted, use encrypt to encrypt them\n")
+		return 1
+	}
+	passphrase, err := newPassphrase()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if err := db.Rekey(passphrase); err != nil {
+		fmt.Fprintf(os.Stderr, "Error changing the passphrase: %v\n", err)
+		return 1
+	}
+	fmt.Println("Changed the passphrase, the old one no longer opens your conversations")
+	if cfg.Settings.Encryption.PassphraseCommand != "" || os.Getenv(passphraseEnv) != "" {
+		fmt.Printf("Update the passphrase command or $%s as well\n", passphraseEnv)
+	}
+	return 0
+}
+
+// dbPath returns the path of the goatmeal database
+func dbPath() string {
+	return os.ExpandEnv("$HOME/.config/goatmeal/goatmeal.db")
+}
+
+// openDB opens the goatmeal database, upgrading it if needed, and unlocks it when it's encrypted
+func openDB(cfg *config.Config) (*database.DB, error) {
+	db, err := database.NewDB(dbPath())
+	if err != nil {
+		return nil, err
+	}
+	if err := unlock(db, cfg, "Passphrase", false); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
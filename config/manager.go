@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/viper"
 )
@@ -14,6 +15,13 @@ type SystemPrompt struct {
 	Content string `mapstructure:"content"`
 }
 
+// SearchPreset is a named set of search filters referenced as @name in /web queries
+type SearchPreset struct {
+	Domains        []string `mapstructure:"domains"`
+	ExcludeDomains []string `mapstructure:"excludedomains"`
+	Topic          string   `mapstructure:"topic"`
+}
+
 // Config represents the application configuration
 type Config struct {
 	APIKeys             map[string]string `mapstructure:"api_keys"`
@@ -22,14 +30,85 @@ type Config struct {
 	CurrentSystemPrompt string           `mapstructure:"current_system_prompt"`
 	SystemPrompts       []SystemPrompt   `mapstructure:"system_prompts"`
 	Settings           Settings         `mapstructure:"settings"`
+	SearchPresets       map[string]SearchPreset `mapstructure:"-"` // Read by loadSearchPresets
 }
 
 // Settings represents application settings
 type Settings struct {
 	OutputGlamour          bool       `mapstructure:"outputglamour"`
 	ConversationRetention  int        `mapstructure:"conversationretention"`
+	ArchiveExpired         bool       `mapstructure:"archiveexpired"` // Archive expired conversations to compressed JSONL instead of deleting them
 	Theme                  ThemeConfig `mapstructure:"theme"`
 	Username               string     `mapstructure:"username"`
+	History                HistorySettings `mapstructure:"history"`
+	Search                 SearchSettings  `mapstructure:"search"`
+	Location               LocationSettings `mapstructure:"location"`
+	AutoTags               []AutoTagRule    `mapstructure:"autotags"`
+	ExportDir              string           `mapstructure:"exportdir"` // Where exports are written, ~/Downloads when empty
+	Encryption             EncryptionSettings `mapstructure:"encryption"`
+}
+
+// EncryptionSettings configures how the passphrase of an encrypted database is read
+// $GOATMEAL_PASSPHRASE takes precedence, and goatmeal prompts for it when neither is set
+type EncryptionSettings struct {
+	PassphraseCommand string `mapstructure:"passphrasecommand"` // Command printing the passphrase, e.g. pass show goatmeal
+}
+
+// AutoTagRule tags conversations that use a system prompt or provider
+// A rule with both set only matches when both do
+type AutoTagRule struct {
+	SystemPrompt string   `mapstructure:"systemprompt"` // System prompt title, e.g. Code Review
+	Provider     string   `mapstructure:"provider"`     // Provider name, e.g. ollama
+	Tags         []string `mapstructure:"tags"`
+}
+
+// Matches reports whether the rule applies to a reply from provider using the system prompt titled systemPrompt
+func (r AutoTagRule) Matches(systemPrompt, provider string) bool {
+	if r.SystemPrompt == "" && r.Provider == "" {
+		return false
+	}
+	if r.SystemPrompt != "" && !strings.EqualFold(r.SystemPrompt, systemPrompt) {
+		return false
+	}
+	if r.Provider != "" && !strings.EqualFold(r.Provider, provider) {
+		return false
+	}
+	return true
+}
+
+// SearchSettings configures the web search backend used by /web
+type SearchSettings struct {
+	Backend  string `mapstructure:"backend"`  // tavily, searx, brave or ddg, defaults to tavily
+	SearxURL string `mapstructure:"searxurl"` // Base URL of a SearxNG instance, e.g. http://localhost:8080
+	CacheTTL int    `mapstructure:"cachettl"` // Minutes results are cached, 0 uses 24 hours and -1 disables the cache
+
+	// Defaults for /web options, each can be overridden in the query
+	SearchDepth       string   `mapstructure:"searchdepth"`       // basic or advanced
+	MaxResults        int      `mapstructure:"maxresults"`        // 0 uses the backend default
+	Topic             string   `mapstructure:"topic"`             // general or news
+	Days              int      `mapstructure:"days"`              // Only news from the last n days
+	TimeRange         string   `mapstructure:"timerange"`         // day, week, month or year
+	IncludeRawContent bool     `mapstructure:"includerawcontent"` // Include page content with results
+	IncludeImages     bool     `mapstructure:"includeimages"`     // Include related images
+	ExcludeDomains    []string `mapstructure:"excludedomains"`    // Domains never included in results
+}
+
+// LocationSettings controls the location context sent with enhanced web searches
+type LocationSettings struct {
+	Mode     string `mapstructure:"mode"`     // manual, auto or off; defaults to manual when a location is set, off otherwise
+	City     string `mapstructure:"city"`
+	Region   string `mapstructure:"region"`
+	Country  string `mapstructure:"country"`
+	Timezone string `mapstructure:"timezone"` // IANA name, e.g. Europe/Berlin
+}
+
+// HistorySettings controls how conversation history is fitted into the model's context window
+type HistorySettings struct {
+	Summarize       bool   `mapstructure:"summarize"`       // Replace older turns with a rolling summary instead of dropping them
+	SummaryProvider string `mapstructure:"summaryprovider"` // Provider used for summaries, defaults to groq
+	SummaryModel    string `mapstructure:"summarymodel"`    // Model used for summaries, defaults to a small fast model
+	ContextWindow   int    `mapstructure:"contextwindow"`   // Context window override in tokens, 0 detects it from the model
+	ReserveTokens   int    `mapstructure:"reservetokens"`   // Tokens kept free for the reply, 0 uses the default
 }
 
 // DefaultSettings contains the default values for settings
@@ -84,6 +163,12 @@ func NewManager() (*Manager, error) {
 			return nil, fmt.Errorf("error parsing config: %w", err)
 		}
 
+		presets, err := loadSearchPresets()
+		if err != nil {
+			return nil, fmt.Errorf("error parsing search presets: %w", err)
+		}
+		config.SearchPresets = presets
+
 		// Ensure theme has a name
 		if config.Settings.Theme.Name == "" {
 			config.Settings.Theme = DefaultThemeConfig()
@@ -189,6 +274,12 @@ func (m *Manager) DeleteSystemPrompt(title string) error {
 	return m.Save()
 }
 
+// SetSearchPresets updates the search presets in the config file
+func (m *Manager) SetSearchPresets(presets map[string]SearchPreset) error {
+	viper.Set("search_presets", presets)
+	return viper.WriteConfig()
+}
+
 // UpdateSettings updates the application settings
 func (m *Manager) UpdateSettings(settings Settings) error {
 	m.config.Settings = settings
@@ -217,6 +308,7 @@ func setDefaultConfig() {
 		"gemini":   "",
 		"deepseek": "",
 		"tavily":   "",
+		"brave":    "",
 		"ollama":   "",
 	})
 
@@ -237,6 +329,44 @@ func setDefaultConfig() {
 	viper.SetDefault("settings", DefaultSettings)
 }
 
+// Dir returns the path to the configuration directory
+func Dir() (string, error) {
+	return getConfigDir()
+}
+
+// loadSearchPresets reads search_presets, where each preset is either a full
+// preset or just a list of domains, e.g. go: [go.dev, pkg.go.dev]
+func loadSearchPresets() (map[string]SearchPreset, error) {
+	presets := make(map[string]SearchPreset)
+	switch raw := viper.Get("search_presets").(type) {
+	case nil:
+	case map[string]SearchPreset:
+		for name, preset := range raw {
+			presets[name] = preset
+		}
+	case map[string]interface{}:
+		for name, value := range raw {
+			var preset SearchPreset
+			switch value := value.(type) {
+			case []interface{}:
+				for _, domain := range value {
+					preset.Domains = append(preset.Domains, fmt.Sprint(domain))
+				}
+			case string:
+				preset.Domains = strings.Fields(strings.ReplaceAll(value, ",", " "))
+			default:
+				if err := viper.UnmarshalKey("search_presets."+name, &preset); err != nil {
+					return nil, fmt.Errorf("preset %s: %w", name, err)
+				}
+			}
+			presets[strings.ToLower(name)] = preset
+		}
+	default:
+		return nil, fmt.Errorf("search_presets must map names to presets")
+	}
+	return presets, nil
+}
+
 // getConfigDir returns the path to the configuration directory
 func getConfigDir() (string, error) {
 	homeDir, err := os.UserHomeDir()
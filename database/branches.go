@@ -0,0 +1,76 @@
+package database
+
+// ActiveBranch returns the messages from the first message down to leafID
+// An empty or unknown leafID uses the most recent message.
+// messages must be ordered by creation time, as returned by GetConversationMessages
+func ActiveBranch(messages []Message, leafID string) []Message {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	byID := make(map[string]Message, len(messages))
+	for _, msg := range messages {
+		byID[msg.ID] = msg
+	}
+	leaf, ok := byID[leafID]
+	if !ok {
+		leaf = messages[len(messages)-1]
+	}
+
+	// Walk up the parents, bounded in case of a cycle in damaged data
+	branch := []Message{leaf}
+	for len(branch) < len(messages) {
+		parent, ok := byID[branch[len(branch)-1].ParentID]
+		if !ok {
+			break
+		}
+		branch = append(branch, parent)
+	}
+
+	for i, j := 0, len(branch)-1; i < j; i, j = i+1, j-1 {
+		branch[i], branch[j] = branch[j], branch[i]
+	}
+	return branch
+}
+
+// Siblings returns the messages sharing the parent of messageID, including itself, oldest first
+func Siblings(messages []Message, messageID string) []Message {
+	var parentID string
+	found := false
+	for _, msg := range messages {
+		if msg.ID == messageID {
+			parentID = msg.ParentID
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil
+	}
+
+	var siblings []Message
+	for _, msg := range messages {
+		if msg.ParentID == parentID {
+			siblings = append(siblings, msg)
+		}
+	}
+	return siblings
+}
+
+// LatestLeaf follows the most recent reply from messageID down to the end of its branch
+func LatestLeaf(messages []Message, messageID string) string {
+	leaf := messageID
+	for steps := 0; steps < len(messages); steps++ {
+		next := ""
+		for _, msg := range messages {
+			if msg.ParentID == leaf {
+				next = msg.ID // Messages are oldest first, so the last match is the latest
+			}
+		}
+		if next == "" {
+			break
+		}
+		leaf = next
+	}
+	return leaf
+}
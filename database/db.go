@@ -1,66 +1,327 @@
 package database
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
+	"unicode"
 )
 
-// GetConversations retrieves a paginated list of conversations
-// If limit is -1, returns all conversations
+// conversationColumns are the conversation columns read by scanConversation
+const conversationColumns = `id, title, provider, model, created_at, updated_at, active_leaf_id, pinned, deleted_at`
+
+// Writes run for every message. They're prepared when the database is opened, preparing on the
+// writer later would wait for the connection held by the transaction that uses them
+const (
+	insertMessageQuery = `
+		INSERT INTO messages (id, conversation_id, role, content, created_at, parent_id,
+			provider, model, system_prompt, system_prompt_hash, params, latency_ms, input_tokens, output_tokens)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	touchConversationQuery = `
+		UPDATE conversations
+		SET updated_at = ?, deleted_at = NULL
+		WHERE id = ?
+	`
+)
+
+// scanConversation reads a row selected with conversationColumns
+func (db *DB) scanConversation(row interface{ Scan(...interface{}) error }, conv *Conversation) error {
+	var deletedAt sql.NullTime
+	err := row.Scan(
+		&conv.ID,
+		&conv.Title,
+		&conv.Provider,
+		&conv.Model,
+		&conv.CreatedAt,
+		&conv.UpdatedAt,
+		&conv.ActiveLeafID,
+		&conv.Pinned,
+		&deletedAt,
+	)
+	if err != nil {
+		return err
+	}
+	conv.DeletedAt = deletedAt.Time
+	conv.Title, err = db.open(conv.Title, "title", conv.ID)
+	return err
+}
+
+// GetConversations retrieves a paginated list of conversations, pinned ones first
+// Conversations in the trash are left out. If limit is -1, returns all conversations
 func (db *DB) GetConversations(offset, limit int) ([]Conversation, error) {
 	var query string
 	var args []interface{}
 
 	if limit == -1 {
 		query = `
-			SELECT id, title, provider, model, created_at, updated_at
+			SELECT ` + conversationColumns + `
 			FROM conversations
-			ORDER BY updated_at DESC
+			WHERE deleted_at IS NULL
+			ORDER BY pinned DESC, updated_at DESC, id DESC
 		`
 	} else {
 		query = `
-			SELECT id, title, provider, model, created_at, updated_at
+			SELECT ` + conversationColumns + `
 			FROM conversations
-			ORDER BY updated_at DESC
+			WHERE deleted_at IS NULL
+			ORDER BY pinned DESC, updated_at DESC, id DESC
 			LIMIT ? OFFSET ?
 		`
 		args = []interface{}{limit, offset}
 	}
 
-	rows, err := db.Query(query, args...)
+	conversations, err := db.queryConversations(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.addListDetails(conversations); err != nil {
+		return nil, err
+	}
+
+	return conversations, nil
+}
+
+// GetConversationsAfter returns up to limit conversations outside the trash that follow after
+// in the order of GetConversations. Unlike an offset, the page doesn't skip or repeat
+// conversations when others are updated or deleted after the previous page was loaded
+func (db *DB) GetConversationsAfter(after Conversation, limit int) ([]Conversation, error) {
+	conversations, err := db.queryConversations(`
+		SELECT `+conversationColumns+`
+		FROM conversations
+		WHERE deleted_at IS NULL AND (pinned, updated_at, id) < (?, ?, ?)
+		ORDER BY pinned DESC, updated_at DESC, id DESC
+		LIMIT ?
+	`, after.Pinned, after.UpdatedAt, after.ID, limit)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.addListDetails(conversations); err != nil {
+		return nil, err
+	}
+	return conversations, nil
+}
+
+// GetConversation retrieves a conversation with its tags and message count, without its messages
+// It finds conversations in the trash as well
+func (db *DB) GetConversation(conversationID string) (*Conversation, error) {
+	conversations, err := db.queryConversations(`
+		SELECT `+conversationColumns+`
+		FROM conversations
+		WHERE id = ?
+	`, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	if len(conversations) == 0 {
+		return nil, fmt.Errorf("error querying conversation: %w", sql.ErrNoRows)
+	}
+	if err := db.addListDetails(conversations); err != nil {
+		return nil, err
+	}
+	return &conversations[0], nil
+}
+
+// GetTaggedConversations returns the conversations outside the trash that have every tag, pinned ones first
+func (db *DB) GetTaggedConversations(tags []string) ([]Conversation, error) {
+	if len(tags) == 0 {
+		return db.GetConversations(0, -1)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(tags)), ", ")
+	args := make([]interface{}, 0, len(tags)+1)
+	for _, tag := range tags {
+		args = append(args, tag)
+	}
+	args = append(args, len(tags))
+
+	// Not prepared, the number of placeholders changes with the tags
+	rows, err := db.Query(`
+		SELECT `+conversationColumns+`
+		FROM conversations
+		WHERE deleted_at IS NULL AND id IN (
+			SELECT ct.conversation_id
+			FROM conversation_tags ct
+			JOIN tags t ON t.id = ct.tag_id
+			WHERE t.name IN (`+placeholders+`)
+			GROUP BY ct.conversation_id
+			HAVING COUNT(DISTINCT t.name) = ?
+		)
+		ORDER BY pinned DESC, updated_at DESC
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying conversations: %w", err)
+	}
+	conversations, err := db.scanConversations(rows)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.addListDetails(conversations); err != nil {
+		return nil, err
+	}
+	return conversations, nil
+}
+
+// ConversationIDs returns the IDs of every conversation, including the ones in the trash
+func (db *DB) ConversationIDs() ([]string, error) {
+	rows, err := db.Query(`SELECT id FROM conversations ORDER BY created_at`)
+	if err != nil {
+		return nil, fmt.Errorf("error querying conversations: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("error scanning conversation: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading conversations: %w", err)
+	}
+	return ids, nil
+}
+
+// GetDeletedConversations returns the conversations in the trash, most recently deleted first
+func (db *DB) GetDeletedConversations() ([]Conversation, error) {
+	conversations, err := db.queryConversations(`
+		SELECT ` + conversationColumns + `
+		FROM conversations
+		WHERE deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.addListDetails(conversations); err != nil {
+		return nil, err
+	}
+	return conversations, nil
+}
+
+// queryConversations returns the conversations selected by query, without their tags and messages
+// The statement is prepared once and kept, so query must not be built with a varying number of
+// placeholders. Such queries run through scanConversations instead
+func (db *DB) queryConversations(query string, args ...interface{}) ([]Conversation, error) {
+	stmt, err := db.prepare(db.DB, query)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := stmt.Query(args...)
 	if err != nil {
 		return nil, fmt.Errorf("error querying conversations: %w", err)
 	}
+	return db.scanConversations(rows)
+}
+
+// scanConversations reads the rows selected with conversationColumns and closes them
+func (db *DB) scanConversations(rows *sql.Rows) ([]Conversation, error) {
 	defer rows.Close()
 
 	var conversations []Conversation
 	for rows.Next() {
 		var conv Conversation
-		err := rows.Scan(
-			&conv.ID,
-			&conv.Title,
-			&conv.Provider,
-			&conv.Model,
-			&conv.CreatedAt,
-			&conv.UpdatedAt,
-		)
-		if err != nil {
+		if err := db.scanConversation(rows, &conv); err != nil {
 			return nil, fmt.Errorf("error scanning conversation: %w", err)
 		}
 		conversations = append(conversations, conv)
 	}
-
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading conversations: %w", err)
+	}
 	return conversations, nil
 }
 
+// maxFilterIDs is the most conversations addListDetails looks up by ID
+// Longer lists read every row instead, which is as fast and stays under SQLite's limit on arguments
+const maxFilterIDs = 500
+
+// addListDetails fills in the tags and message counts of conversations with one query each
+func (db *DB) addListDetails(conversations []Conversation) error {
+	if len(conversations) == 0 {
+		return nil
+	}
+	tagFilter, countFilter := "1=1", "1=1"
+	var args []interface{}
+	if len(conversations) <= maxFilterIDs {
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(conversations)), ", ")
+		tagFilter = "ct.conversation_id IN (" + placeholders + ")"
+		countFilter = "conversation_id IN (" + placeholders + ")"
+		for _, conv := range conversations {
+			args = append(args, conv.ID)
+		}
+	}
+
+	// Neither query is prepared, the number of placeholders changes with the conversations
+	tags := make(map[string][]string)
+	rows, err := db.Query(`
+		SELECT ct.conversation_id, t.name
+		FROM conversation_tags ct
+		JOIN tags t ON t.id = ct.tag_id
+		WHERE `+tagFilter+`
+		ORDER BY t.name
+	`, args...)
+	if err != nil {
+		return fmt.Errorf("error querying conversation tags: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var conversationID, name string
+		if err := rows.Scan(&conversationID, &name); err != nil {
+			return fmt.Errorf("error scanning conversation tag: %w", err)
+		}
+		tags[conversationID] = append(tags[conversationID], name)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error reading conversation tags: %w", err)
+	}
+
+	counts := make(map[string]int)
+	rows, err = db.Query(`
+		SELECT conversation_id, COUNT(*)
+		FROM messages
+		WHERE `+countFilter+`
+		GROUP BY conversation_id
+	`, args...)
+	if err != nil {
+		return fmt.Errorf("error counting messages: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var conversationID string
+		var count int
+		if err := rows.Scan(&conversationID, &count); err != nil {
+			return fmt.Errorf("error counting messages: %w", err)
+		}
+		counts[conversationID] = count
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error counting messages: %w", err)
+	}
+
+	for i := range conversations {
+		conversations[i].Tags = tags[conversations[i].ID]
+		conversations[i].MessageCount = counts[conversations[i].ID]
+	}
+	return nil
+}
+
 // GetConversationMessages retrieves all messages for a conversation
 func (db *DB) GetConversationMessages(conversationID string) ([]Message, error) {
-	rows, err := db.Query(`
-		SELECT id, role, content, created_at
+	stmt, err := db.prepare(db.DB, `
+		SELECT id, role, content, created_at, parent_id,
+			provider, model, system_prompt, system_prompt_hash, params, latency_ms, input_tokens, output_tokens
 		FROM messages
 		WHERE conversation_id = ?
 		ORDER BY created_at ASC
-	`, conversationID)
+	`)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := stmt.Query(conversationID)
 	if err != nil {
 		return nil, fmt.Errorf("error querying messages: %w", err)
 	}
@@ -69,18 +330,35 @@ func (db *DB) GetConversationMessages(conversationID string) ([]Message, error)
 	var messages []Message
 	for rows.Next() {
 		var msg Message
+		var latencyMs int64
 		err := rows.Scan(
 			&msg.ID,
 			&msg.Role,
 			&msg.Content,
 			&msg.CreatedAt,
+			&msg.ParentID,
+			&msg.Provider,
+			&msg.Model,
+			&msg.SystemPrompt,
+			&msg.SystemPromptHash,
+			&msg.Params,
+			&latencyMs,
+			&msg.InputTokens,
+			&msg.OutputTokens,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("error scanning message: %w", err)
 		}
+		if msg.Content, err = db.open(msg.Content, "content", msg.ID); err != nil {
+			return nil, err
+		}
 		msg.ConversationID = conversationID
+		msg.Latency = time.Duration(latencyMs) * time.Millisecond
 		messages = append(messages, msg)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading messages: %w", err)
+	}
 
 	return messages, nil
 }
@@ -92,6 +370,7 @@ func (db *DB) SaveConversation(conv *Conversation) error {
 		return fmt.Errorf("error beginning transaction: %w", err)
 	}
 	defer tx.Rollback()
+	index := db.newIndexUpdates()
 
 	// Check if conversation exists
 	var exists bool
@@ -102,19 +381,24 @@ func (db *DB) SaveConversation(conv *Conversation) error {
 
 	if !exists {
 		// Insert new conversation
+		title, err := db.seal(conv.Title, "title", conv.ID)
+		if err != nil {
+			return err
+		}
 		_, err = tx.Exec(`
 			INSERT INTO conversations (id, title, provider, model, created_at, updated_at)
 			VALUES (?, ?, ?, ?, ?, ?)
-		`, conv.ID, conv.Title, conv.Provider, conv.Model, conv.CreatedAt, conv.UpdatedAt)
+		`, conv.ID, title, conv.Provider, conv.Model, conv.CreatedAt, conv.UpdatedAt)
 		if err != nil {
 			return fmt.Errorf("error inserting conversation: %w", err)
 		}
+		index.setTitle(conv.ID, conv.Title)
 	}
 
-	// Update conversation's updated_at timestamp
+	// Update conversation's updated_at timestamp, new messages take it out of the trash
 	_, err = tx.Exec(`
 		UPDATE conversations
-		SET updated_at = ?
+		SET updated_at = ?, deleted_at = NULL
 		WHERE id = ?
 	`, time.Now(), conv.ID)
 	if err != nil {
@@ -123,16 +407,23 @@ func (db *DB) SaveConversation(conv *Conversation) error {
 
 	// Insert messages
 	for _, msg := range conv.Messages {
-		_, err = tx.Exec(`
-			INSERT INTO messages (id, conversation_id, role, content, created_at)
-			VALUES (?, ?, ?, ?, ?)
-		`, msg.ID, conv.ID, msg.Role, msg.Content, msg.CreatedAt)
-		if err != nil {
-			return fmt.Errorf("error inserting message: %w", err)
+		msg.ConversationID = conv.ID
+		if err := db.insertMessage(tx, &msg, index); err != nil {
+			return err
 		}
 	}
 
-	return tx.Commit()
+	// The new messages continue the active branch
+	if len(conv.Messages) > 0 {
+		if err := setActiveLeaf(tx, conv.ID, conv.Messages[len(conv.Messages)-1].ID); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return index.apply()
 }
 
 // AddMessage adds a single message to an existing conversation
@@ -143,84 +434,315 @@ func (db *DB) AddMessage(msg *Message) error {
 	}
 	defer tx.Rollback()
 
-	// Update conversation's updated_at timestamp
-	_, err = tx.Exec(`
-		UPDATE conversations
-		SET updated_at = ?
-		WHERE id = ?
-	`, time.Now(), msg.ConversationID)
+	// Update conversation's updated_at timestamp, new messages take it out of the trash
+	stmt, err := db.prepare(db.writer, touchConversationQuery)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Stmt(stmt).Exec(time.Now(), msg.ConversationID)
 	if err != nil {
 		return fmt.Errorf("error updating conversation timestamp: %w", err)
 	}
 
 	// Insert the message
+	index := db.newIndexUpdates()
+	if err := db.insertMessage(tx, msg, index); err != nil {
+		return err
+	}
+
+	// The new message continues the active branch
+	if err := setActiveLeaf(tx, msg.ConversationID, msg.ID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return index.apply()
+}
+
+// ImportConversation saves a conversation from another source with its own timestamps,
+// messages, tags and active branch. It returns false without saving anything when a
+// conversation with the same ID exists, including in the trash
+func (db *DB) ImportConversation(conv *Conversation) (bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	err = tx.QueryRow("SELECT EXISTS(SELECT 1 FROM conversations WHERE id = ?)", conv.ID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("error checking conversation existence: %w", err)
+	}
+	if exists {
+		return false, nil
+	}
+
+	index := db.newIndexUpdates()
+	if err := db.insertConversation(tx, conv, index); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("error committing import: %w", err)
+	}
+	return true, index.apply()
+}
+
+// MergeConversation adds a conversation from a backup, or when a conversation with the same ID
+// exists, the messages and tags it doesn't have yet. The active branch follows the backup when
+// it was updated more recently. It returns whether the conversation was new and the number of
+// messages added to an existing one
+func (db *DB) MergeConversation(conv *Conversation) (bool, int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, 0, fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+	index := db.newIndexUpdates()
+
+	var updatedAt time.Time
+	err = tx.QueryRow("SELECT updated_at FROM conversations WHERE id = ?", conv.ID).Scan(&updatedAt)
+	if err == sql.ErrNoRows {
+		if err := db.insertConversation(tx, conv, index); err != nil {
+			return false, 0, err
+		}
+		if err := tx.Commit(); err != nil {
+			return false, 0, fmt.Errorf("error committing merge: %w", err)
+		}
+		return true, 0, index.apply()
+	}
+	if err != nil {
+		return false, 0, fmt.Errorf("error checking conversation existence: %w", err)
+	}
+
+	added := 0
+	for _, msg := range conv.Messages {
+		var exists bool
+		if err := tx.QueryRow("SELECT EXISTS(SELECT 1 FROM messages WHERE id = ?)", msg.ID).Scan(&exists); err != nil {
+			return false, 0, fmt.Errorf("error checking message existence: %w", err)
+		}
+		if exists {
+			continue
+		}
+		msg.ConversationID = conv.ID
+		if err := db.insertMessage(tx, &msg, index); err != nil {
+			return false, 0, err
+		}
+		added++
+	}
+	if err := addConversationTags(tx, conv.ID, conv.Tags); err != nil {
+		return false, 0, err
+	}
+
+	if conv.UpdatedAt.After(updatedAt) {
+		_, err = tx.Exec(`
+			UPDATE conversations
+			SET updated_at = ?, active_leaf_id = ?, pinned = pinned OR ?
+			WHERE id = ?
+		`, conv.UpdatedAt, conv.ActiveLeafID, conv.Pinned, conv.ID)
+	} else {
+		_, err = tx.Exec(`UPDATE conversations SET pinned = pinned OR ? WHERE id = ?`, conv.Pinned, conv.ID)
+	}
+	if err != nil {
+		return false, 0, fmt.Errorf("error updating conversation: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, 0, fmt.Errorf("error committing merge: %w", err)
+	}
+	return false, added, index.apply()
+}
+
+// insertConversation saves a new conversation with its own timestamps, messages, tags and active branch
+func (db *DB) insertConversation(tx *sql.Tx, conv *Conversation, index *indexUpdates) error {
+	title, err := db.seal(conv.Title, "title", conv.ID)
+	if err != nil {
+		return err
+	}
+	deletedAt := sql.NullTime{Time: conv.DeletedAt, Valid: !conv.DeletedAt.IsZero()}
 	_, err = tx.Exec(`
-		INSERT INTO messages (id, conversation_id, role, content, created_at)
-		VALUES (?, ?, ?, ?, ?)
-	`, msg.ID, msg.ConversationID, msg.Role, msg.Content, msg.CreatedAt)
+		INSERT INTO conversations (id, title, provider, model, created_at, updated_at, active_leaf_id, pinned, deleted_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, conv.ID, title, conv.Provider, conv.Model, conv.CreatedAt, conv.UpdatedAt, conv.ActiveLeafID, conv.Pinned, deletedAt)
 	if err != nil {
-		return fmt.Errorf("error inserting message: %w", err)
+		return fmt.Errorf("error inserting conversation: %w", err)
+	}
+	index.setTitle(conv.ID, conv.Title)
+
+	for _, msg := range conv.Messages {
+		msg.ConversationID = conv.ID
+		if err := db.insertMessage(tx, &msg, index); err != nil {
+			return err
+		}
+	}
+	return addConversationTags(tx, conv.ID, conv.Tags)
+}
+
+// Snapshot writes a consistent copy of the database to path, which must not exist yet
+func (db *DB) Snapshot(path string) error {
+	if _, err := db.Exec(`VACUUM INTO ?`, path); err != nil {
+		return fmt.Errorf("error writing database snapshot: %w", err)
+	}
+	return nil
+}
+
+// SetActiveLeaf selects the branch ending in messageID as the one shown in the chat
+func (db *DB) SetActiveLeaf(conversationID, messageID string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := setActiveLeaf(tx, conversationID, messageID); err != nil {
+		return err
 	}
 
 	return tx.Commit()
 }
 
+func setActiveLeaf(tx *sql.Tx, conversationID, messageID string) error {
+	_, err := tx.Exec(`
+		UPDATE conversations
+		SET active_leaf_id = ?
+		WHERE id = ?
+	`, messageID, conversationID)
+	if err != nil {
+		return fmt.Errorf("error updating active branch: %w", err)
+	}
+	return nil
+}
+
+// insertMessage inserts a message with its provenance
+func (db *DB) insertMessage(tx *sql.Tx, msg *Message, index *indexUpdates) error {
+	content, err := db.seal(msg.Content, "content", msg.ID)
+	if err != nil {
+		return err
+	}
+	stmt, err := db.prepare(db.writer, insertMessageQuery)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Stmt(stmt).Exec(msg.ID, msg.ConversationID, msg.Role, content, msg.CreatedAt, msg.ParentID,
+		msg.Provider, msg.Model, msg.SystemPrompt, msg.SystemPromptHash, msg.Params,
+		msg.Latency.Milliseconds(), msg.InputTokens, msg.OutputTokens)
+	if err != nil {
+		return fmt.Errorf("error inserting message: %w", err)
+	}
+	index.addMessage(msg.ID, msg.ConversationID, msg.Content)
+	return nil
+}
+
+// indexTitle adds a title to the in-memory search index of an encrypted database after it was saved
+// The on-disk index of an unencrypted database is kept up to date by triggers
+func (db *DB) indexTitle(conversationID, title string) error {
+	if db.index == nil {
+		return nil
+	}
+	return db.index.setTitle(conversationID, title)
+}
+
 // UpdateConversationTitle updates the title of a conversation
 func (db *DB) UpdateConversationTitle(conversationID, title string) error {
-	_, err := db.Exec(`
+	sealed, err := db.seal(title, "title", conversationID)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`
 		UPDATE conversations
 		SET title = ?, updated_at = ?
 		WHERE id = ?
-	`, title, time.Now(), conversationID)
+	`, sealed, time.Now(), conversationID)
 	
 	if err != nil {
 		return fmt.Errorf("error updating conversation title: %w", err)
 	}
 	
-	return nil
+	return db.indexTitle(conversationID, title)
 }
 
-// DeleteConversation deletes a conversation and its messages
+// DeleteConversation moves a conversation to the trash
 func (db *DB) DeleteConversation(conversationID string) error {
+	_, err := db.Exec(`
+		UPDATE conversations
+		SET deleted_at = ?
+		WHERE id = ?
+	`, time.Now(), conversationID)
+	if err != nil {
+		return fmt.Errorf("error deleting conversation: %w", err)
+	}
+	return nil
+}
+
+// RestoreConversation takes a conversation out of the trash
+func (db *DB) RestoreConversation(conversationID string) error {
+	_, err := db.Exec(`
+		UPDATE conversations
+		SET deleted_at = NULL
+		WHERE id = ?
+	`, conversationID)
+	if err != nil {
+		return fmt.Errorf("error restoring conversation: %w", err)
+	}
+	return nil
+}
+
+// PurgeConversation permanently deletes a conversation and its messages
+func (db *DB) PurgeConversation(conversationID string) error {
 	tx, err := db.Begin()
 	if err != nil {
 		return fmt.Errorf("error beginning transaction: %w", err)
 	}
 	defer tx.Rollback()
 
-	// Delete messages first due to foreign key constraint
-	_, err = tx.Exec(`DELETE FROM messages WHERE conversation_id = ?`, conversationID)
-	if err != nil {
-		return fmt.Errorf("error deleting messages: %w", err)
+	index := db.newIndexUpdates()
+	if err := db.deleteConversation(tx, conversationID, index); err != nil {
+		return err
+	}
+	if err := deleteUnusedTags(tx); err != nil {
+		return err
 	}
 
-	// Delete conversation
-	_, err = tx.Exec(`DELETE FROM conversations WHERE id = ?`, conversationID)
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return index.apply()
+}
+
+// deleteConversation deletes a conversation with its messages, summary and tag links
+func (db *DB) deleteConversation(tx *sql.Tx, conversationID string, index *indexUpdates) error {
+	// The messages, summary and tag links are deleted by their ON DELETE CASCADE
+	_, err := tx.Exec(`DELETE FROM conversations WHERE id = ?`, conversationID)
 	if err != nil {
 		return fmt.Errorf("error deleting conversation: %w", err)
 	}
+	index.removeConversation(conversationID)
+	return nil
+}
 
-	return tx.Commit()
+// SetConversationPinned pins or unpins a conversation
+func (db *DB) SetConversationPinned(conversationID string, pinned bool) error {
+	_, err := db.Exec(`
+		UPDATE conversations
+		SET pinned = ?
+		WHERE id = ?
+	`, pinned, conversationID)
+	if err != nil {
+		return fmt.Errorf("error updating conversation pin: %w", err)
+	}
+	return nil
 }
 
-// ExportConversation retrieves a complete conversation with all messages for export
-func (db *DB) ExportConversation(conversationID string) (*Conversation, error) {
+// ExportConversation retrieves a conversation with its messages for export
+// With wholeTree the messages of every branch are included, otherwise only the active branch
+func (db *DB) ExportConversation(conversationID string, wholeTree bool) (*Conversation, error) {
 	// Get the conversation details
-	var conv Conversation
-	err := db.QueryRow(`
-		SELECT id, title, provider, model, created_at, updated_at
-		FROM conversations
-		WHERE id = ?
-	`, conversationID).Scan(
-		&conv.ID,
-		&conv.Title,
-		&conv.Provider,
-		&conv.Model,
-		&conv.CreatedAt,
-		&conv.UpdatedAt,
-	)
+	conv, err := db.GetConversation(conversationID)
 	if err != nil {
-		return nil, fmt.Errorf("error querying conversation: %w", err)
+		return nil, err
 	}
 
 	// Get all messages for the conversation
@@ -228,7 +750,372 @@ func (db *DB) ExportConversation(conversationID string) (*Conversation, error) {
 	if err != nil {
 		return nil, fmt.Errorf("error querying messages: %w", err)
 	}
-	conv.Messages = messages
+	if wholeTree {
+		conv.Messages = messages
+	} else {
+		conv.Messages = ActiveBranch(messages, conv.ActiveLeafID)
+	}
+
+	return conv, nil
+} 
+
+// GetConversationSummary retrieves the rolling summary for a conversation
+// Returns nil if the conversation has no summary
+func (db *DB) GetConversationSummary(conversationID string) (*ConversationSummary, error) {
+	var summary ConversationSummary
+	err := db.QueryRow(`
+		SELECT conversation_id, summary, summarized_turns, updated_at
+		FROM conversation_summaries
+		WHERE conversation_id = ?
+	`, conversationID).Scan(
+		&summary.ConversationID,
+		&summary.Summary,
+		&summary.SummarizedTurns,
+		&summary.UpdatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error querying conversation summary: %w", err)
+	}
+	if summary.Summary, err = db.open(summary.Summary, "summary", conversationID); err != nil {
+		return nil, err
+	}
+
+	return &summary, nil
+}
+
+// SaveConversationSummary stores or replaces the rolling summary for a conversation
+func (db *DB) SaveConversationSummary(summary *ConversationSummary) error {
+	sealed, err := db.seal(summary.Summary, "summary", summary.ConversationID)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`
+		INSERT INTO conversation_summaries (conversation_id, summary, summarized_turns, updated_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(conversation_id) DO UPDATE SET
+			summary = excluded.summary,
+			summarized_turns = excluded.summarized_turns,
+			updated_at = excluded.updated_at
+	`, summary.ConversationID, sealed, summary.SummarizedTurns, summary.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("error saving conversation summary: %w", err)
+	}
 
-	return &conv, nil
-} 
\ No newline at end of file
+	return nil
+}
+
+// DeleteConversationSummary removes the rolling summary of a conversation
+func (db *DB) DeleteConversationSummary(conversationID string) error {
+	_, err := db.Exec(`DELETE FROM conversation_summaries WHERE conversation_id = ?`, conversationID)
+	if err != nil {
+		return fmt.Errorf("error deleting conversation summary: %w", err)
+	}
+	return nil
+}
+
+// GetSearchCache retrieves a cached search response no older than maxAge
+// Returns nil if there is no fresh entry. Encrypted databases don't cache searches
+func (db *DB) GetSearchCache(query, domains, backend, options string, maxAge time.Duration) (*SearchCacheEntry, error) {
+	if db.Encrypted() {
+		return nil, nil
+	}
+	var entry SearchCacheEntry
+	err := db.QueryRow(`
+		SELECT query, domains, backend, options, response, created_at
+		FROM search_cache
+		WHERE query = ? AND domains = ? AND backend = ? AND options = ?
+	`, query, domains, backend, options).Scan(
+		&entry.Query,
+		&entry.Domains,
+		&entry.Backend,
+		&entry.Options,
+		&entry.Response,
+		&entry.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error querying search cache: %w", err)
+	}
+
+	if time.Since(entry.CreatedAt) > maxAge {
+		return nil, nil
+	}
+
+	return &entry, nil
+}
+
+// SaveSearchCache stores or replaces a cached search response
+// Nothing is stored in an encrypted database, the queries and responses would be in plaintext
+func (db *DB) SaveSearchCache(entry *SearchCacheEntry) error {
+	if db.Encrypted() {
+		return nil
+	}
+	_, err := db.Exec(`
+		INSERT INTO search_cache (query, domains, backend, options, response, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(query, domains, backend, options) DO UPDATE SET
+			response = excluded.response,
+			created_at = excluded.created_at
+	`, entry.Query, entry.Domains, entry.Backend, entry.Options, entry.Response, entry.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error saving search cache: %w", err)
+	}
+
+	return nil
+}
+
+// CleanupSearchCache deletes cached search responses older than maxAge
+func (db *DB) CleanupSearchCache(maxAge time.Duration) error {
+	cutoff := time.Now().Add(-maxAge)
+
+	_, err := db.Exec(`
+		DELETE FROM search_cache
+		WHERE created_at < ?
+	`, cutoff)
+	if err != nil {
+		return fmt.Errorf("error cleaning up search cache: %w", err)
+	}
+
+	return nil
+}
+
+// SearchConversations finds conversations whose title or messages match query
+// Results are ordered by relevance with one hit per conversation
+func (db *DB) SearchConversations(query string, limit int) ([]SearchHit, error) {
+	match := ftsQuery(query)
+	if match == "" {
+		return nil, nil
+	}
+	if db.Encrypted() {
+		return db.searchEncrypted(match, limit)
+	}
+
+	rows, err := db.Query(`
+		WITH message_hits AS (
+			SELECT conversation_id, message_id,
+				snippet(messages_fts, 0, ?, ?, '…', 12) AS snippet,
+				bm25(messages_fts) AS score
+			FROM messages_fts
+			WHERE messages_fts MATCH ?
+		),
+		title_hits AS (
+			SELECT conversation_id,
+				highlight(conversations_fts, 0, ?, ?) AS title,
+				bm25(conversations_fts) AS score
+			FROM conversations_fts
+			WHERE conversations_fts MATCH ?
+		),
+		best_messages AS (
+			SELECT conversation_id, message_id, snippet, score,
+				ROW_NUMBER() OVER (PARTITION BY conversation_id ORDER BY score) AS position
+			FROM message_hits
+		)
+		SELECT c.id, COALESCE(t.title, c.title), COALESCE(m.message_id, ''), COALESCE(m.snippet, ''), c.updated_at
+		FROM conversations c
+		LEFT JOIN title_hits t ON t.conversation_id = c.id
+		LEFT JOIN best_messages m ON m.conversation_id = c.id AND m.position = 1
+		WHERE (t.conversation_id IS NOT NULL OR m.conversation_id IS NOT NULL) AND c.deleted_at IS NULL
+		ORDER BY MIN(COALESCE(t.score * 2, 0), COALESCE(m.score, 0)), c.updated_at DESC
+		LIMIT ?
+	`, HighlightStart, HighlightEnd, match, HighlightStart, HighlightEnd, match, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error searching conversations: %w", err)
+	}
+	defer rows.Close()
+
+	var hits []SearchHit
+	for rows.Next() {
+		var hit SearchHit
+		if err := rows.Scan(&hit.ConversationID, &hit.Title, &hit.MessageID, &hit.Snippet, &hit.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning search result: %w", err)
+		}
+		hits = append(hits, hit)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating search results: %w", err)
+	}
+
+	return hits, nil
+}
+
+// ftsQuery turns user input into an FTS5 query matching every word as a prefix
+// Quoting each word keeps FTS5 operators and punctuation in the input from causing syntax errors
+func ftsQuery(input string) string {
+	var terms []string
+	for _, word := range strings.Fields(input) {
+		word = strings.ReplaceAll(word, `"`, "")
+		if word == "" {
+			continue
+		}
+		terms = append(terms, `"`+word+`"*`)
+	}
+	return strings.Join(terms, " ")
+}
+
+// GetTags returns every tag in use with the number of conversations outside the trash it is on
+func (db *DB) GetTags() ([]Tag, error) {
+	rows, err := db.Query(`
+		SELECT t.name, COUNT(*)
+		FROM tags t
+		JOIN conversation_tags ct ON ct.tag_id = t.id
+		JOIN conversations c ON c.id = ct.conversation_id AND c.deleted_at IS NULL
+		GROUP BY t.id
+		ORDER BY t.name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("error querying tags: %w", err)
+	}
+	defer rows.Close()
+
+	var tags []Tag
+	for rows.Next() {
+		var tag Tag
+		if err := rows.Scan(&tag.Name, &tag.Conversations); err != nil {
+			return nil, fmt.Errorf("error scanning tag: %w", err)
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// GetConversationTags returns the tag names of a conversation in alphabetical order
+func (db *DB) GetConversationTags(conversationID string) ([]string, error) {
+	rows, err := db.Query(`
+		SELECT t.name
+		FROM conversation_tags ct
+		JOIN tags t ON t.id = ct.tag_id
+		WHERE ct.conversation_id = ?
+		ORDER BY t.name
+	`, conversationID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying conversation tags: %w", err)
+	}
+	defer rows.Close()
+
+	var tags []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("error scanning conversation tag: %w", err)
+		}
+		tags = append(tags, name)
+	}
+	return tags, rows.Err()
+}
+
+// AddConversationTags adds tags to a conversation, keeping the ones it already has
+func (db *DB) AddConversationTags(conversationID string, tags ...string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := addConversationTags(tx, conversationID, tags); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RemoveConversationTags removes tags from a conversation
+func (db *DB) RemoveConversationTags(conversationID string, tags ...string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, name := range tags {
+		_, err := tx.Exec(`
+			DELETE FROM conversation_tags
+			WHERE conversation_id = ? AND tag_id = (SELECT id FROM tags WHERE name = ?)
+		`, conversationID, NormalizeTag(name))
+		if err != nil {
+			return fmt.Errorf("error removing tag: %w", err)
+		}
+	}
+	if err := deleteUnusedTags(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SetConversationTags replaces the tags of a conversation
+func (db *DB) SetConversationTags(conversationID string, tags []string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM conversation_tags WHERE conversation_id = ?`, conversationID); err != nil {
+		return fmt.Errorf("error clearing conversation tags: %w", err)
+	}
+	if err := addConversationTags(tx, conversationID, tags); err != nil {
+		return err
+	}
+	if err := deleteUnusedTags(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// addConversationTags links a conversation to tags, creating the tags that don't exist yet
+func addConversationTags(tx *sql.Tx, conversationID string, tags []string) error {
+	for _, name := range tags {
+		name = NormalizeTag(name)
+		if name == "" {
+			continue
+		}
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO tags (name) VALUES (?)`, name); err != nil {
+			return fmt.Errorf("error creating tag: %w", err)
+		}
+		_, err := tx.Exec(`
+			INSERT OR IGNORE INTO conversation_tags (conversation_id, tag_id)
+			SELECT ?, id FROM tags WHERE name = ?
+		`, conversationID, name)
+		if err != nil {
+			return fmt.Errorf("error adding tag: %w", err)
+		}
+	}
+	return nil
+}
+
+// deleteUnusedTags removes tags no conversation has anymore
+func deleteUnusedTags(tx *sql.Tx) error {
+	_, err := tx.Exec(`DELETE FROM tags WHERE id NOT IN (SELECT tag_id FROM conversation_tags)`)
+	if err != nil {
+		return fmt.Errorf("error deleting unused tags: %w", err)
+	}
+	return nil
+}
+
+// NormalizeTag returns the stored form of a tag name: lowercase, without a leading #,
+// with runs of spaces and underscores turned into single dashes
+func NormalizeTag(name string) string {
+	name = strings.ToLower(strings.TrimLeft(strings.TrimSpace(name), "#"))
+	name = strings.Join(strings.FieldsFunc(name, func(r rune) bool {
+		return unicode.IsSpace(r) || r == '_' || r == ','
+	}), "-")
+	return strings.Trim(name, "-")
+}
+
+// ParseTags splits a list of tags separated by spaces or commas into normalized, unique names
+func ParseTags(input string) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, field := range strings.FieldsFunc(input, func(r rune) bool {
+		return unicode.IsSpace(r) || r == ','
+	}) {
+		if tag := NormalizeTag(field); tag != "" && !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
@@ -0,0 +1,180 @@
+package database
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestDB opens a new database in a temporary directory
+func newTestDB(t *testing.T) *DB {
+	t.Helper()
+	db, err := NewDB(filepath.Join(t.TempDir(), "goatmeal.db"))
+	if err != nil {
+		t.Fatalf("error opening database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// testConversation returns a conversation with messages replies and, for even i, the tag "even"
+func testConversation(i, messages int) *Conversation {
+	updated := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(i) * time.Minute)
+	conv := &Conversation{
+		ID:        fmt.Sprintf("conv-%04d", i),
+		Title:     fmt.Sprintf("Conversation %d", i),
+		Provider:  "openai",
+		Model:     "gpt-4o",
+		CreatedAt: updated,
+		UpdatedAt: updated,
+		Tags:      []string{"all"},
+	}
+	if i%2 == 0 {
+		conv.Tags = append(conv.Tags, "even")
+	}
+	parentID := ""
+	for m := 0; m < messages; m++ {
+		id := fmt.Sprintf("%s-msg-%d", conv.ID, m)
+		conv.Messages = append(conv.Messages, Message{
+			ID:        id,
+			Role:      "user",
+			Content:   fmt.Sprintf("message %d", m),
+			CreatedAt: updated,
+			ParentID:  parentID,
+		})
+		parentID = id
+	}
+	if messages > 0 {
+		conv.ActiveLeafID = parentID
+	}
+	return conv
+}
+
+func TestListDetails(t *testing.T) {
+	tests := []struct {
+		name          string
+		conversations int
+	}{
+		{"filtered by ID", 10},
+		{"at the ID limit", maxFilterIDs},
+		{"over the ID limit", maxFilterIDs + 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			for i := 0; i < tt.conversations; i++ {
+				if _, err := db.ImportConversation(testConversation(i, i%3+1)); err != nil {
+					t.Fatalf("error importing conversation %d: %v", i, err)
+				}
+			}
+
+			check := func(name string, conversations []Conversation, want int) {
+				t.Helper()
+				if len(conversations) != want {
+					t.Fatalf("%s: got %d conversations, want %d", name, len(conversations), want)
+				}
+				for _, conv := range conversations {
+					var i int
+					fmt.Sscanf(conv.ID, "conv-%d", &i)
+					if conv.MessageCount != i%3+1 {
+						t.Errorf("%s: %s has %d messages, want %d", name, conv.ID, conv.MessageCount, i%3+1)
+					}
+					wantTags := 1
+					if i%2 == 0 {
+						wantTags = 2
+					}
+					if len(conv.Tags) != wantTags {
+						t.Errorf("%s: %s has tags %v", name, conv.ID, conv.Tags)
+					}
+				}
+			}
+
+			all, err := db.GetConversations(0, -1)
+			if err != nil {
+				t.Fatalf("GetConversations: %v", err)
+			}
+			check("GetConversations", all, tt.conversations)
+
+			even, err := db.GetTaggedConversations([]string{"even"})
+			if err != nil {
+				t.Fatalf("GetTaggedConversations: %v", err)
+			}
+			check("GetTaggedConversations", even, (tt.conversations+1)/2)
+
+			for _, conv := range all {
+				if err := db.DeleteConversation(conv.ID); err != nil {
+					t.Fatalf("DeleteConversation: %v", err)
+				}
+			}
+			deleted, err := db.GetDeletedConversations()
+			if err != nil {
+				t.Fatalf("GetDeletedConversations: %v", err)
+			}
+			check("GetDeletedConversations", deleted, tt.conversations)
+		})
+	}
+}
+
+func TestGetConversationsAfter(t *testing.T) {
+	db := newTestDB(t)
+	for i := 0; i < 25; i++ {
+		if _, err := db.ImportConversation(testConversation(i, 1)); err != nil {
+			t.Fatalf("error importing conversation %d: %v", i, err)
+		}
+	}
+	if err := db.SetConversationPinned("conv-0003", true); err != nil {
+		t.Fatalf("error pinning conversation: %v", err)
+	}
+
+	first, err := db.GetConversations(0, 10)
+	if err != nil {
+		t.Fatalf("GetConversations: %v", err)
+	}
+	if first[0].ID != "conv-0003" {
+		t.Fatalf("got %s first, want the pinned conversation", first[0].ID)
+	}
+
+	// Between pages, a conversation on the next page is updated and one on the first is deleted.
+	// An offset would now repeat a conversation, and then skip one
+	if err := db.AddMessage(&Message{ID: "new", ConversationID: "conv-0010", Role: "user", Content: "again", CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("AddMessage: %v", err)
+	}
+	if err := db.DeleteConversation(first[1].ID); err != nil {
+		t.Fatalf("DeleteConversation: %v", err)
+	}
+
+	listed := make(map[string]bool)
+	for _, conv := range first {
+		listed[conv.ID] = true
+	}
+	cursor := first[len(first)-1]
+	for {
+		page, err := db.GetConversationsAfter(cursor, 10)
+		if err != nil {
+			t.Fatalf("GetConversationsAfter: %v", err)
+		}
+		if len(page) == 0 {
+			break
+		}
+		for _, conv := range page {
+			if listed[conv.ID] {
+				t.Errorf("%s is listed twice", conv.ID)
+			}
+			listed[conv.ID] = true
+			if conv.MessageCount != 1 {
+				t.Errorf("%s has %d messages, want 1", conv.ID, conv.MessageCount)
+			}
+		}
+		cursor = page[len(page)-1]
+	}
+
+	// Only the conversation that moved to the top since the first page is missing
+	for i := 0; i < 25; i++ {
+		id := fmt.Sprintf("conv-%04d", i)
+		if listed[id] == (id == "conv-0010") {
+			t.Errorf("%s listed: %v", id, listed[id])
+		}
+	}
+}
@@ -0,0 +1,392 @@
+package database
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+)
+
+var (
+	// ErrLocked is returned when an encrypted database is used before Unlock
+	ErrLocked = errors.New("database is encrypted and locked")
+	// ErrWrongPassphrase is returned by Unlock when the passphrase doesn't match
+	ErrWrongPassphrase = errors.New("wrong passphrase")
+	// ErrNotEncrypted is returned when changing the key of a database that isn't encrypted
+	ErrNotEncrypted = errors.New("database is not encrypted")
+	// ErrAlreadyEncrypted is returned when encrypting a database a second time
+	ErrAlreadyEncrypted = errors.New("database is already encrypted")
+	// ErrArchiveEncrypted is returned when retention would archive conversations of an encrypted
+	// database, since the archive would hold them in plain text
+	ErrArchiveEncrypted = errors.New("conversations of an encrypted database can't be archived")
+)
+
+// encryptedPrefix marks an encrypted value, followed by the base64 nonce and ciphertext
+// The full-text search triggers skip values starting with it
+const encryptedPrefix = "enc:v1:"
+
+// keyCheck is encrypted with the key to tell a wrong passphrase from damaged data
+const keyCheck = "goatmeal"
+
+// kdfParams are the Argon2id settings a key is derived with, stored with the database
+type kdfParams struct {
+	Salt    []byte
+	Time    uint32
+	Memory  uint32 // KiB
+	Threads uint8
+}
+
+// newKDFParams returns the Argon2id settings for a new key with a random salt
+func newKDFParams() (kdfParams, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return kdfParams{}, fmt.Errorf("error generating salt: %w", err)
+	}
+	return kdfParams{Salt: salt, Time: 3, Memory: 64 * 1024, Threads: 4}, nil
+}
+
+// crypter encrypts and decrypts values with AES-256-GCM
+type crypter struct {
+	aead cipher.AEAD
+}
+
+// newCrypter derives the key for passphrase
+func newCrypter(passphrase string, params kdfParams) (*crypter, error) {
+	key := argon2.IDKey([]byte(passphrase), params.Salt, params.Time, params.Memory, params.Threads, 32)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("error creating cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("error creating cipher: %w", err)
+	}
+	return &crypter{aead: aead}, nil
+}
+
+// seal encrypts value, bound to id so it can't be moved to another row
+func (c *crypter) seal(value, id string) string {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		panic(fmt.Sprintf("error generating nonce: %v", err))
+	}
+	sealed := c.aead.Seal(nonce, nonce, []byte(value), []byte(id))
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed)
+}
+
+// open decrypts a value sealed for id. Values that aren't encrypted are returned as they are
+func (c *crypter) open(value, id string) (string, error) {
+	if !strings.HasPrefix(value, encryptedPrefix) {
+		return value, nil
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+	if err != nil || len(sealed) < c.aead.NonceSize() {
+		return "", fmt.Errorf("error decrypting %s: malformed value", id)
+	}
+	nonce := sealed[:c.aead.NonceSize()]
+	plain, err := c.aead.Open(nil, nonce, sealed[len(nonce):], []byte(id))
+	if err != nil {
+		return "", fmt.Errorf("error decrypting %s: %w", id, err)
+	}
+	return string(plain), nil
+}
+
+// loadEncryption reads the key settings of an encrypted database, nil when it isn't encrypted
+func loadEncryption(db *sql.DB) (*kdfParams, string, error) {
+	var params kdfParams
+	var check string
+	err := db.QueryRow(`
+		SELECT salt, time_cost, memory_cost, threads, key_check
+		FROM encryption
+		WHERE id = 1
+	`).Scan(&params.Salt, &params.Time, &params.Memory, &params.Threads, &check)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("error reading encryption settings: %w", err)
+	}
+	return &params, check, nil
+}
+
+// Encrypted reports whether conversations are stored encrypted
+func (db *DB) Encrypted() bool {
+	return db.kdf != nil
+}
+
+// Locked reports whether the database is encrypted and Unlock hasn't been called yet
+func (db *DB) Locked() bool {
+	return db.kdf != nil && db.crypt == nil
+}
+
+// Unlock derives the key of an encrypted database from passphrase and builds the in-memory
+// full-text index of the decrypted conversations
+func (db *DB) Unlock(passphrase string) error {
+	if db.kdf == nil {
+		return ErrNotEncrypted
+	}
+	c, err := newCrypter(passphrase, *db.kdf)
+	if err != nil {
+		return err
+	}
+	if check, err := c.open(db.keyCheck, "encryption"); err != nil || check != keyCheck {
+		return ErrWrongPassphrase
+	}
+	return db.useKey(c)
+}
+
+// useKey starts encrypting with c and indexes the decrypted conversations for search
+func (db *DB) useKey(c *crypter) error {
+	index, err := newSearchIndex()
+	if err != nil {
+		return err
+	}
+	db.crypt = c
+	if err := db.buildSearchIndex(index); err != nil {
+		db.crypt = nil
+		index.Close()
+		return err
+	}
+	if db.index != nil {
+		db.index.Close()
+	}
+	db.index = index
+	return nil
+}
+
+// EnableEncryption encrypts every message, title and summary with a key derived from passphrase
+// The plaintext search index and web search cache are cleared, and the file is vacuumed
+// so no plaintext is left in unused pages or the write-ahead log
+func (db *DB) EnableEncryption(passphrase string) error {
+	if db.kdf != nil {
+		return ErrAlreadyEncrypted
+	}
+	params, err := newKDFParams()
+	if err != nil {
+		return err
+	}
+	c, err := newCrypter(passphrase, params)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := recrypt(tx, nil, c); err != nil {
+		return err
+	}
+	// The triggers already dropped the rows of encrypted messages, this catches anything left
+	// Optimizing merges the index segments, so the deleted terms aren't kept in them
+	if _, err := tx.Exec(`
+		DELETE FROM messages_fts;
+		DELETE FROM conversations_fts;
+		INSERT INTO messages_fts (messages_fts) VALUES ('optimize');
+		INSERT INTO conversations_fts (conversations_fts) VALUES ('optimize');
+		DELETE FROM search_cache;
+	`); err != nil {
+		return fmt.Errorf("error clearing plaintext indexes: %w", err)
+	}
+	check, err := saveEncryption(tx, params, c)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing encryption: %w", err)
+	}
+
+	if _, err := db.Exec(`VACUUM`); err != nil {
+		return fmt.Errorf("error vacuuming database: %w", err)
+	}
+	// The write-ahead log still holds the plaintext pages until it's checkpointed
+	if _, err := db.Exec(`PRAGMA wal_checkpoint(TRUNCATE)`); err != nil {
+		return fmt.Errorf("error checkpointing database: %w", err)
+	}
+
+	db.kdf = &params
+	db.keyCheck = check
+	return db.useKey(c)
+}
+
+// DisableEncryption decrypts everything again and rebuilds the on-disk search index
+func (db *DB) DisableEncryption() error {
+	if db.kdf == nil {
+		return ErrNotEncrypted
+	}
+	if db.crypt == nil {
+		return ErrLocked
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// The triggers index the decrypted messages and titles as they're written
+	if err := recrypt(tx, db.crypt, nil); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM encryption`); err != nil {
+		return fmt.Errorf("error removing encryption settings: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing decryption: %w", err)
+	}
+
+	db.kdf = nil
+	db.keyCheck = ""
+	db.crypt = nil
+	if db.index != nil {
+		db.index.Close()
+		db.index = nil
+	}
+	return nil
+}
+
+// Rekey encrypts everything again with a new key derived from passphrase
+// The salt is replaced as well, so the old passphrase no longer opens the database
+func (db *DB) Rekey(passphrase string) error {
+	if db.kdf == nil {
+		return ErrNotEncrypted
+	}
+	if db.crypt == nil {
+		return ErrLocked
+	}
+	params, err := newKDFParams()
+	if err != nil {
+		return err
+	}
+	c, err := newCrypter(passphrase, params)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := recrypt(tx, db.crypt, c); err != nil {
+		return err
+	}
+	check, err := saveEncryption(tx, params, c)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing new key: %w", err)
+	}
+
+	db.kdf = &params
+	db.keyCheck = check
+	db.crypt = c
+	return nil
+}
+
+// saveEncryption stores the key settings of an encrypted database and returns the sealed key check
+func saveEncryption(tx *sql.Tx, params kdfParams, c *crypter) (string, error) {
+	check := c.seal(keyCheck, "encryption")
+	_, err := tx.Exec(`
+		INSERT OR REPLACE INTO encryption (id, kdf, salt, time_cost, memory_cost, threads, key_check, created_at)
+		VALUES (1, 'argon2id', ?, ?, ?, ?, ?, ?)
+	`, params.Salt, params.Time, params.Memory, params.Threads, check, time.Now())
+	if err != nil {
+		return "", fmt.Errorf("error saving encryption settings: %w", err)
+	}
+	return check, nil
+}
+
+// recrypt rewrites every title, message and summary, decrypting with from and encrypting with to
+// A nil from reads plaintext and a nil to writes plaintext
+func recrypt(tx *sql.Tx, from, to *crypter) error {
+	tables := []struct {
+		name, id, column string
+	}{
+		{"conversations", "id", "title"},
+		{"messages", "id", "content"},
+		{"conversation_summaries", "conversation_id", "summary"},
+	}
+	for _, table := range tables {
+		if err := recryptColumn(tx, table.name, table.id, table.column, from, to); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recryptColumn rewrites a column of every row in table, see recrypt
+func recryptColumn(tx *sql.Tx, table, idColumn, column string, from, to *crypter) error {
+	rows, err := tx.Query(fmt.Sprintf(`SELECT %s, %s FROM %s`, idColumn, column, table))
+	if err != nil {
+		return fmt.Errorf("error reading %s: %w", table, err)
+	}
+	values := make(map[string]string)
+	for rows.Next() {
+		var id, value string
+		if err := rows.Scan(&id, &value); err != nil {
+			rows.Close()
+			return fmt.Errorf("error reading %s: %w", table, err)
+		}
+		values[id] = value
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error reading %s: %w", table, err)
+	}
+
+	update := fmt.Sprintf(`UPDATE %s SET %s = ? WHERE %s = ?`, table, column, idColumn)
+	for id, value := range values {
+		if from != nil {
+			if value, err = from.open(value, aad(column, id)); err != nil {
+				return err
+			}
+		}
+		if to != nil {
+			value = to.seal(value, aad(column, id))
+		}
+		if _, err := tx.Exec(update, value, id); err != nil {
+			return fmt.Errorf("error updating %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// aad names the column and row a value belongs to, so an encrypted value
+// can't be moved to another row or column without failing to decrypt
+func aad(column, id string) string {
+	return column + ":" + id
+}
+
+// seal encrypts a value of the column of row id when the database is encrypted
+func (db *DB) seal(value, column, id string) (string, error) {
+	if db.kdf == nil {
+		return value, nil
+	}
+	if db.crypt == nil {
+		return "", ErrLocked
+	}
+	return db.crypt.seal(value, aad(column, id)), nil
+}
+
+// open decrypts a value of the column of row id when the database is encrypted
+func (db *DB) open(value, column, id string) (string, error) {
+	if db.kdf == nil {
+		return value, nil
+	}
+	if db.crypt == nil {
+		return "", ErrLocked
+	}
+	return db.crypt.open(value, aad(column, id))
+}
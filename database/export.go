@@ -0,0 +1,124 @@
+package database
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ExportedConversation is the JSON form of a conversation written by exports and archives
+type ExportedConversation struct {
+	ID           string            `json:"id"`
+	Title        string            `json:"title"`
+	Provider     string            `json:"provider"`
+	Model        string            `json:"model"`
+	CreatedAt    time.Time         `json:"created_at"`
+	UpdatedAt    time.Time         `json:"updated_at"`
+	Pinned       bool              `json:"pinned,omitempty"`
+	Tags         []string          `json:"tags,omitempty"`
+	ActiveLeafID string            `json:"active_leaf_id,omitempty"`
+	Messages     []ExportedMessage `json:"messages"`
+}
+
+// ExportedMessage is the JSON form of a message in an ExportedConversation
+type ExportedMessage struct {
+	ID               string          `json:"id"`
+	ParentID         string          `json:"parent_id,omitempty"`
+	Role             string          `json:"role"`
+	Content          string          `json:"content"`
+	Timestamp        time.Time       `json:"timestamp"`
+	Provider         string          `json:"provider,omitempty"`
+	Model            string          `json:"model,omitempty"`
+	SystemPrompt     string          `json:"system_prompt,omitempty"`
+	SystemPromptHash string          `json:"system_prompt_hash,omitempty"`
+	Params           json.RawMessage `json:"params,omitempty"`
+	LatencyMS        int64           `json:"latency_ms,omitempty"`
+	InputTokens      int             `json:"input_tokens,omitempty"`
+	OutputTokens     int             `json:"output_tokens,omitempty"`
+}
+
+// NewExportedConversation converts a conversation loaded by ExportConversation to its JSON form
+func NewExportedConversation(conv *Conversation) ExportedConversation {
+	exported := ExportedConversation{
+		ID:           conv.ID,
+		Title:        conv.Title,
+		Provider:     conv.Provider,
+		Model:        conv.Model,
+		CreatedAt:    conv.CreatedAt,
+		UpdatedAt:    conv.UpdatedAt,
+		Pinned:       conv.Pinned,
+		Tags:         conv.Tags,
+		ActiveLeafID: conv.ActiveLeafID,
+		Messages:     make([]ExportedMessage, len(conv.Messages)),
+	}
+	for i, msg := range conv.Messages {
+		exported.Messages[i] = ExportedMessage{
+			ID:               msg.ID,
+			ParentID:         msg.ParentID,
+			Role:             msg.Role,
+			Content:          msg.Content,
+			Timestamp:        msg.CreatedAt,
+			Provider:         msg.Provider,
+			Model:            msg.Model,
+			SystemPrompt:     msg.SystemPrompt,
+			SystemPromptHash: msg.SystemPromptHash,
+			LatencyMS:        msg.Latency.Milliseconds(),
+			InputTokens:      msg.InputTokens,
+			OutputTokens:     msg.OutputTokens,
+		}
+		if json.Valid([]byte(msg.Params)) {
+			exported.Messages[i].Params = json.RawMessage(msg.Params)
+		}
+	}
+	return exported
+}
+
+// ArchiveConversations writes conversations with every branch and their tags to path
+// as gzip compressed JSONL, one ExportedConversation per line
+func (db *DB) ArchiveConversations(path string, conversationIDs []string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("error creating archive directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("error creating archive: %w", err)
+	}
+
+	// A partial archive is removed, so the conversations are only deleted once all of them are saved
+	err = db.writeArchive(file, conversationIDs)
+	if closeErr := file.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("error writing archive: %w", closeErr)
+	}
+	if err != nil {
+		os.Remove(path)
+	}
+	return err
+}
+
+// writeArchive writes the JSONL archive of the conversations to file
+func (db *DB) writeArchive(file *os.File, conversationIDs []string) error {
+	buffered := bufio.NewWriter(file)
+	compressed := gzip.NewWriter(buffered)
+	encoder := json.NewEncoder(compressed)
+	for _, id := range conversationIDs {
+		conv, err := db.ExportConversation(id, true)
+		if err != nil {
+			return fmt.Errorf("error archiving conversation %s: %w", id, err)
+		}
+		if err := encoder.Encode(NewExportedConversation(conv)); err != nil {
+			return fmt.Errorf("error archiving conversation %s: %w", id, err)
+		}
+	}
+
+	if err := compressed.Close(); err != nil {
+		return fmt.Errorf("error writing archive: %w", err)
+	}
+	if err := buffered.Flush(); err != nil {
+		return fmt.Errorf("error writing archive: %w", err)
+	}
+	return file.Sync()
+}
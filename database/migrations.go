@@ -0,0 +1,386 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// ErrNewerSchema is returned when the database was written by a newer version of goatmeal
+var ErrNewerSchema = errors.New("database was created by a newer version of goatmeal")
+
+// migration is a single schema change, identified by its version
+type migration struct {
+	version int
+	name    string
+	up      string
+}
+
+// migrations are applied in order. Never edit or reorder a released migration,
+// always append a new one. The first migrations use IF NOT EXISTS because
+// databases created before versioning already contain their tables
+var migrations = []migration{
+	{
+		version: 1,
+		name:    "create conversations and messages",
+		up: `
+CREATE TABLE IF NOT EXISTS conversations (
+    id TEXT PRIMARY KEY,
+    title TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    provider TEXT NOT NULL,
+    model TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS messages (
+    id TEXT PRIMARY KEY,
+    conversation_id TEXT NOT NULL,
+    role TEXT NOT NULL,
+    content TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (conversation_id) REFERENCES conversations(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_messages_conversation_id ON messages(conversation_id);
+CREATE INDEX IF NOT EXISTS idx_conversations_created_at ON conversations(created_at);
+`,
+	},
+	{
+		version: 2,
+		name:    "create conversation summaries",
+		up: `
+CREATE TABLE IF NOT EXISTS conversation_summaries (
+    conversation_id TEXT PRIMARY KEY,
+    summary TEXT NOT NULL,
+    summarized_turns INTEGER NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (conversation_id) REFERENCES conversations(id) ON DELETE CASCADE
+);
+`,
+	},
+	{
+		version: 3,
+		name:    "create search cache",
+		up: `
+CREATE TABLE IF NOT EXISTS search_cache (
+    query TEXT NOT NULL,
+    domains TEXT NOT NULL,
+    backend TEXT NOT NULL,
+    options TEXT NOT NULL,
+    response TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (query, domains, backend, options)
+);
+`,
+	},
+	{
+		version: 4,
+		name:    "add full-text search",
+		up: `
+CREATE VIRTUAL TABLE messages_fts USING fts5(
+    content,
+    message_id UNINDEXED,
+    conversation_id UNINDEXED,
+    tokenize = 'porter unicode61'
+);
+
+CREATE VIRTUAL TABLE conversations_fts USING fts5(
+    title,
+    conversation_id UNINDEXED,
+    tokenize = 'porter unicode61'
+);
+
+INSERT INTO messages_fts (content, message_id, conversation_id)
+SELECT content, id, conversation_id FROM messages;
+
+INSERT INTO conversations_fts (title, conversation_id)
+SELECT title, id FROM conversations;
+
+CREATE TRIGGER messages_fts_insert AFTER INSERT ON messages BEGIN
+    INSERT INTO messages_fts (content, message_id, conversation_id)
+    VALUES (new.content, new.id, new.conversation_id);
+END;
+
+CREATE TRIGGER messages_fts_delete AFTER DELETE ON messages BEGIN
+    DELETE FROM messages_fts WHERE message_id = old.id;
+END;
+
+CREATE TRIGGER messages_fts_update AFTER UPDATE OF content, conversation_id ON messages BEGIN
+    DELETE FROM messages_fts WHERE message_id = old.id;
+    INSERT INTO messages_fts (content, message_id, conversation_id)
+    VALUES (new.content, new.id, new.conversation_id);
+END;
+
+CREATE TRIGGER conversations_fts_insert AFTER INSERT ON conversations BEGIN
+    INSERT INTO conversations_fts (title, conversation_id)
+    VALUES (new.title, new.id);
+END;
+
+CREATE TRIGGER conversations_fts_delete AFTER DELETE ON conversations BEGIN
+    DELETE FROM conversations_fts WHERE conversation_id = old.id;
+END;
+
+CREATE TRIGGER conversations_fts_update AFTER UPDATE OF title ON conversations BEGIN
+    DELETE FROM conversations_fts WHERE conversation_id = old.id;
+    INSERT INTO conversations_fts (title, conversation_id)
+    VALUES (new.title, new.id);
+END;
+`,
+	},
+	{
+		version: 5,
+		name:    "add message provenance",
+		up: `
+ALTER TABLE messages ADD COLUMN provider TEXT NOT NULL DEFAULT '';
+ALTER TABLE messages ADD COLUMN model TEXT NOT NULL DEFAULT '';
+ALTER TABLE messages ADD COLUMN system_prompt TEXT NOT NULL DEFAULT '';
+ALTER TABLE messages ADD COLUMN system_prompt_hash TEXT NOT NULL DEFAULT '';
+ALTER TABLE messages ADD COLUMN params TEXT NOT NULL DEFAULT '';
+ALTER TABLE messages ADD COLUMN latency_ms INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE messages ADD COLUMN input_tokens INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE messages ADD COLUMN output_tokens INTEGER NOT NULL DEFAULT 0;
+`,
+	},
+	{
+		version: 6,
+		name:    "add conversation branches",
+		up: `
+ALTER TABLE messages ADD COLUMN parent_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE conversations ADD COLUMN active_leaf_id TEXT NOT NULL DEFAULT '';
+
+-- Existing conversations are a single branch, each message follows the one before it
+UPDATE messages SET parent_id = COALESCE((
+    SELECT p.id FROM messages p
+    WHERE p.conversation_id = messages.conversation_id
+      AND (p.created_at < messages.created_at OR (p.created_at = messages.created_at AND p.rowid < messages.rowid))
+    ORDER BY p.created_at DESC, p.rowid DESC
+    LIMIT 1
+), '');
+
+CREATE INDEX idx_messages_parent_id ON messages(conversation_id, parent_id);
+`,
+	},
+	{
+		version: 7,
+		name:    "create tags",
+		up: `
+CREATE TABLE tags (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE conversation_tags (
+    conversation_id TEXT NOT NULL,
+    tag_id INTEGER NOT NULL,
+    PRIMARY KEY (conversation_id, tag_id),
+    FOREIGN KEY (conversation_id) REFERENCES conversations(id) ON DELETE CASCADE,
+    FOREIGN KEY (tag_id) REFERENCES tags(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_conversation_tags_tag_id ON conversation_tags(tag_id);
+`,
+	},
+	{
+		version: 8,
+		name:    "add pinned conversations",
+		up: `
+ALTER TABLE conversations ADD COLUMN pinned INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX idx_conversations_updated_at ON conversations(pinned, updated_at);
+`,
+	},
+	{
+		version: 9,
+		name:    "add conversation trash",
+		up: `
+ALTER TABLE conversations ADD COLUMN deleted_at TIMESTAMP;
+
+CREATE INDEX idx_conversations_deleted_at ON conversations(deleted_at);
+`,
+	},
+	{
+		version: 10,
+		name:    "add encryption at rest",
+		up: `
+CREATE TABLE encryption (
+    id INTEGER PRIMARY KEY CHECK (id = 1),
+    kdf TEXT NOT NULL,
+    salt BLOB NOT NULL,
+    time_cost INTEGER NOT NULL,
+    memory_cost INTEGER NOT NULL,
+    threads INTEGER NOT NULL,
+    key_check TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+-- Encrypted values are searched through an in-memory index, keep them out of the on-disk one
+DROP TRIGGER messages_fts_insert;
+DROP TRIGGER messages_fts_update;
+DROP TRIGGER conversations_fts_insert;
+DROP TRIGGER conversations_fts_update;
+
+CREATE TRIGGER messages_fts_insert AFTER INSERT ON messages
+WHEN substr(new.content, 1, 7) <> 'enc:v1:' BEGIN
+    INSERT INTO messages_fts (content, message_id, conversation_id)
+    VALUES (new.content, new.id, new.conversation_id);
+END;
+
+CREATE TRIGGER messages_fts_update AFTER UPDATE OF content, conversation_id ON messages BEGIN
+    DELETE FROM messages_fts WHERE message_id = old.id;
+    INSERT INTO messages_fts (content, message_id, conversation_id)
+    SELECT new.content, new.id, new.conversation_id
+    WHERE substr(new.content, 1, 7) <> 'enc:v1:';
+END;
+
+CREATE TRIGGER conversations_fts_insert AFTER INSERT ON conversations
+WHEN substr(new.title, 1, 7) <> 'enc:v1:' BEGIN
+    INSERT INTO conversations_fts (title, conversation_id)
+    VALUES (new.title, new.id);
+END;
+
+CREATE TRIGGER conversations_fts_update AFTER UPDATE OF title ON conversations BEGIN
+    DELETE FROM conversations_fts WHERE conversation_id = old.id;
+    INSERT INTO conversations_fts (title, conversation_id)
+    SELECT new.title, new.id
+    WHERE substr(new.title, 1, 7) <> 'enc:v1:';
+END;
+`,
+	},
+	{
+		version: 11,
+		name:    "remove orphaned rows",
+		up: `
+-- Foreign keys weren't enforced before, remove the rows their ON DELETE CASCADE would have
+DELETE FROM messages WHERE conversation_id NOT IN (SELECT id FROM conversations);
+DELETE FROM conversation_summaries WHERE conversation_id NOT IN (SELECT id FROM conversations);
+DELETE FROM conversation_tags WHERE conversation_id NOT IN (SELECT id FROM conversations);
+DELETE FROM conversation_tags WHERE tag_id NOT IN (SELECT id FROM tags);
+`,
+	},
+}
+
+// SchemaVersion returns the schema version this build of goatmeal writes
+func SchemaVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+// migrate brings the database schema up to date
+// The database file at path is backed up before any migration runs on existing data
+func migrate(db *sql.DB, path string) error {
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TIMESTAMP NOT NULL
+		)
+	`); err != nil {
+		return fmt.Errorf("error creating schema_migrations: %w", err)
+	}
+
+	var current int
+	if err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return fmt.Errorf("error reading schema version: %w", err)
+	}
+
+	latest := SchemaVersion()
+	if current > latest {
+		return fmt.Errorf("%w: schema version %d, this version supports up to %d; please upgrade goatmeal", ErrNewerSchema, current, latest)
+	}
+	if current == latest {
+		return nil
+	}
+
+	hasData, err := hasUserTables(db)
+	if err != nil {
+		return err
+	}
+	if hasData {
+		backupPath, err := backupDatabase(db, path, current)
+		if err != nil {
+			return fmt.Errorf("error backing up database before migrating: %w", err)
+		}
+		if backupPath != "" {
+			fmt.Fprintf(os.Stderr, "Backed up database to %s before upgrading it\n", backupPath)
+		}
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		if err := applyMigration(db, m); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// applyMigration runs a migration and records it in a single transaction
+// Another goatmeal instance may have applied it since the version was read, so it's checked
+// again once the transaction holds the write lock
+func applyMigration(db *sql.DB, m migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting migration %d: %w", m.version, err)
+	}
+	defer tx.Rollback()
+
+	var current int
+	if err := tx.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return fmt.Errorf("error reading schema version: %w", err)
+	}
+	if current >= m.version {
+		return nil
+	}
+
+	if _, err := tx.Exec(m.up); err != nil {
+		return fmt.Errorf("error applying migration %d (%s): %w", m.version, m.name, err)
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO schema_migrations (version, name, applied_at)
+		VALUES (?, ?, ?)
+	`, m.version, m.name, time.Now()); err != nil {
+		return fmt.Errorf("error recording migration %d: %w", m.version, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing migration %d: %w", m.version, err)
+	}
+	return nil
+}
+
+// hasUserTables reports whether the database contains any tables besides schema_migrations
+func hasUserTables(db *sql.DB) (bool, error) {
+	var count int
+	err := db.QueryRow(`
+		SELECT COUNT(*) FROM sqlite_master
+		WHERE type = 'table' AND name NOT LIKE 'sqlite_%' AND name != 'schema_migrations'
+	`).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("error inspecting database: %w", err)
+	}
+	return count > 0, nil
+}
+
+// backupDatabase writes a consistent copy of the database next to path
+// It returns the backup path, or "" for in-memory databases
+func backupDatabase(db *sql.DB, path string, version int) (string, error) {
+	if path == "" || path == ":memory:" || strings.HasPrefix(path, "file::memory:") {
+		return "", nil
+	}
+
+	backupPath := fmt.Sprintf("%s.v%d-%s.bak", path, version, time.Now().Format("20060102-150405"))
+	if _, err := db.Exec(`VACUUM INTO ?`, backupPath); err != nil {
+		// Another instance upgrading at the same time already wrote this backup
+		if _, statErr := os.Stat(backupPath); statErr == nil {
+			return "", nil
+		}
+		return "", err
+	}
+	return backupPath, nil
+}
@@ -2,72 +2,200 @@ package database
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"path/filepath"
+	"sync"
 	"time"
 
 	_ "modernc.org/sqlite"
 )
 
-const schema = `
-CREATE TABLE IF NOT EXISTS conversations (
-    id TEXT PRIMARY KEY,
-    title TEXT NOT NULL,
-    created_at TIMESTAMP NOT NULL,
-    updated_at TIMESTAMP NOT NULL,
-    provider TEXT NOT NULL,
-    model TEXT NOT NULL
-);
-
-CREATE TABLE IF NOT EXISTS messages (
-    id TEXT PRIMARY KEY,
-    conversation_id TEXT NOT NULL,
-    role TEXT NOT NULL,
-    content TEXT NOT NULL,
-    created_at TIMESTAMP NOT NULL,
-    FOREIGN KEY (conversation_id) REFERENCES conversations(id) ON DELETE CASCADE
-);
-
-CREATE INDEX IF NOT EXISTS idx_messages_conversation_id ON messages(conversation_id);
-CREATE INDEX IF NOT EXISTS idx_conversations_created_at ON conversations(created_at);
-`
-
 // DB represents the database connection
+// Reads use a pool of read-only connections, and every write goes through a single writer
+// connection, so writes from the UI's goroutines queue up instead of failing with "database is locked"
 type DB struct {
 	*sql.DB
+	writer   *sql.DB
+	stmtsMu  sync.Mutex
+	stmts    map[stmtKey]*sql.Stmt // Prepared statements by connection pool and query
+	kdf      *kdfParams   // Key settings when the database is encrypted
+	keyCheck string       // Encrypted known value that tells whether a passphrase is right
+	crypt    *crypter     // Set once an encrypted database is unlocked
+	index    *searchIndex // Full-text index of an unlocked encrypted database
 }
 
+// Options of every connection. A connection waits up to 5 seconds for another goatmeal to finish
+// writing, and foreign keys are off in SQLite unless enabled per connection
+const connectionOptions = "_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)"
+
 // NewDB creates a new database connection
 func NewDB(path string) (*DB, error) {
-	db, err := sql.Open("sqlite", path)
+	// WAL lets readers and the writer work at the same time, also across goatmeal instances
+	// The writer takes the write lock when a transaction begins, so a transaction waits for
+	// another instance's instead of failing when it's halfway through
+	writer, err := sql.Open("sqlite", path+"?"+connectionOptions+"&_pragma=journal_mode(WAL)&_txlock=immediate")
 	if err != nil {
 		return nil, fmt.Errorf("error opening database: %w", err)
 	}
+	writer.SetMaxOpenConns(1)
+	writer.SetConnMaxLifetime(0)
 
-	if err := db.Ping(); err != nil {
+	if err := writer.Ping(); err != nil {
+		writer.Close()
 		return nil, fmt.Errorf("error connecting to database: %w", err)
 	}
 
-	if _, err := db.Exec(schema); err != nil {
-		return nil, fmt.Errorf("error creating schema: %w", err)
+	if err := migrate(writer, path); err != nil {
+		writer.Close()
+		return nil, err
+	}
+
+	db, err := sql.Open("sqlite", path+"?"+connectionOptions+"&_pragma=query_only(1)")
+	if err != nil {
+		writer.Close()
+		return nil, fmt.Errorf("error opening database: %w", err)
 	}
 
-	return &DB{db}, nil
+	kdf, keyCheck, err := loadEncryption(db)
+	if err != nil {
+		db.Close()
+		writer.Close()
+		return nil, err
+	}
+
+	d := &DB{DB: db, writer: writer, stmts: make(map[stmtKey]*sql.Stmt), kdf: kdf, keyCheck: keyCheck}
+	for _, query := range []string{insertMessageQuery, touchConversationQuery} {
+		if _, err := d.prepare(writer, query); err != nil {
+			d.Close()
+			return nil, err
+		}
+	}
+	return d, nil
 }
 
-// CleanupOldConversations deletes conversations older than the retention period
-func (db *DB) CleanupOldConversations(retentionDays int) error {
-	cutoff := time.Now().AddDate(0, 0, -retentionDays)
-	
-	_, err := db.Exec(`
-		DELETE FROM conversations 
-		WHERE created_at < ?
-	`, cutoff)
-	
+// Exec runs a statement that writes on the writer connection
+func (db *DB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return db.writer.Exec(query, args...)
+}
+
+// Begin starts a write transaction on the writer connection
+// Other writes wait until it's committed or rolled back
+func (db *DB) Begin() (*sql.Tx, error) {
+	return db.writer.Begin()
+}
+
+// Close closes the prepared statements, both connection pools and the search index
+func (db *DB) Close() error {
+	db.stmtsMu.Lock()
+	for _, stmt := range db.stmts {
+		stmt.Close()
+	}
+	db.stmts = nil
+	db.stmtsMu.Unlock()
+
+	if db.index != nil {
+		db.index.Close()
+	}
+	return errors.Join(db.DB.Close(), db.writer.Close())
+}
+
+// stmtKey identifies a prepared statement
+type stmtKey struct {
+	pool  *sql.DB
+	query string
+}
+
+// prepare returns query prepared on pool, preparing it the first time it's used
+// Statements for the writer must be prepared in NewDB, see insertMessageQuery
+func (db *DB) prepare(pool *sql.DB, query string) (*sql.Stmt, error) {
+	db.stmtsMu.Lock()
+	defer db.stmtsMu.Unlock()
+
+	key := stmtKey{pool, query}
+	if stmt, ok := db.stmts[key]; ok {
+		return stmt, nil
+	}
+	if db.stmts == nil {
+		return nil, errors.New("database is closed")
+	}
+	stmt, err := pool.Prepare(query)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing statement: %w", err)
+	}
+	db.stmts[key] = stmt
+	return stmt, nil
+}
+
+// CleanupOldConversations permanently deletes the conversations returned by ExpiredConversations
+// With an archiveDir, expired conversations that aren't in the trash are first written to a
+// compressed JSONL file there. An encrypted database returns ErrArchiveEncrypted instead, without
+// deleting anything. It returns the number of conversations deleted
+func (db *DB) CleanupOldConversations(retentionDays int, archiveDir string) (int, error) {
+	expired, err := db.ExpiredConversations(retentionDays)
+	if err != nil {
+		return 0, err
+	}
+	if len(expired) == 0 {
+		return 0, nil
+	}
+	if archiveDir != "" && db.Encrypted() {
+		return 0, ErrArchiveEncrypted
+	}
+
+	if archiveDir != "" {
+		var ids []string
+		for _, conv := range expired {
+			if conv.DeletedAt.IsZero() {
+				ids = append(ids, conv.ID)
+			}
+		}
+		if len(ids) > 0 {
+			path := filepath.Join(archiveDir, "conversations-"+time.Now().Format("20060102-150405")+".jsonl.gz")
+			if err := db.ArchiveConversations(path, ids); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	tx, err := db.Begin()
 	if err != nil {
-		return fmt.Errorf("error cleaning up old conversations: %w", err)
+		return 0, fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+	index := db.newIndexUpdates()
+
+	for _, conv := range expired {
+		if err := db.deleteConversation(tx, conv.ID, index); err != nil {
+			return 0, fmt.Errorf("error cleaning up old conversations: %w", err)
+		}
 	}
-	
-	return nil
+	if err := deleteUnusedTags(tx); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("error cleaning up old conversations: %w", err)
+	}
+	return len(expired), index.apply()
+}
+
+// ExpiredConversations returns the unpinned conversations not updated within the retention period
+// and the conversations in the trash for longer than that, least recently changed first.
+// A retention of 0 or less keeps every conversation
+func (db *DB) ExpiredConversations(retentionDays int) ([]Conversation, error) {
+	if retentionDays <= 0 {
+		return nil, nil
+	}
+	cutoff := time.Now().AddDate(0, 0, -retentionDays)
+
+	return db.queryConversations(`
+		SELECT `+conversationColumns+`
+		FROM conversations
+		WHERE (deleted_at IS NULL AND pinned = 0 AND updated_at < ?)
+		   OR (deleted_at IS NOT NULL AND deleted_at < ?)
+		ORDER BY COALESCE(deleted_at, updated_at)
+	`, cutoff, cutoff)
 }
 
 // Message represents a chat message
@@ -77,6 +205,21 @@ type Message struct {
 	Role           string    // Can be "user", "assistant", or "search"
 	Content        string
 	CreatedAt      time.Time
+	ParentID       string // Message this one follows, empty for the first message
+	Provenance
+}
+
+// Provenance records how a message was generated
+// Messages saved before provenance was tracked have zero values
+type Provenance struct {
+	Provider         string
+	Model            string
+	SystemPrompt     string // Title of the system prompt used
+	SystemPromptHash string // Short SHA-256 of the system prompt text
+	Params           string // JSON encoded generation parameters
+	Latency          time.Duration
+	InputTokens      int
+	OutputTokens     int
 }
 
 // Conversation represents a chat conversation
@@ -87,5 +230,49 @@ type Conversation struct {
 	Model     string
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	ActiveLeafID string // Last message of the branch shown in the chat, empty for the latest message
+	Pinned    bool // Pinned conversations are listed first and never cleaned up
+	DeletedAt time.Time // When the conversation was moved to the trash, zero if it isn't there
+	Tags      []string // Tag names in alphabetical order, filled by GetConversations
+	MessageCount int // Messages in every branch, filled by GetConversations
 	Messages  []Message
-} 
\ No newline at end of file
+} 
+
+// Tag is a label shared by any number of conversations
+type Tag struct {
+	Name          string
+	Conversations int // Number of conversations with the tag
+}
+
+// ConversationSummary represents the rolling summary of a conversation's older turns
+type ConversationSummary struct {
+	ConversationID  string
+	Summary         string
+	SummarizedTurns int // Number of leading user/assistant turns covered by Summary
+	UpdatedAt       time.Time
+}
+
+// SearchCacheEntry represents a cached web search response
+type SearchCacheEntry struct {
+	Query     string // Normalized query
+	Domains   string // Normalized domain filters
+	Backend   string
+	Options   string // Normalized remaining search options
+	Response  string // JSON encoded search response
+	CreatedAt time.Time
+}
+
+// Markers placed around matching terms in SearchHit snippets
+const (
+	HighlightStart = "\x02"
+	HighlightEnd   = "\x03"
+)
+
+// SearchHit is a conversation matching a full-text search
+type SearchHit struct {
+	ConversationID string
+	Title          string // Conversation title, with matching terms marked when the title matched
+	MessageID      string // Best matching message, empty when only the title matched
+	Snippet        string // Excerpt of the matching message with matching terms marked
+	UpdatedAt      time.Time
+}
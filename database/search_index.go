@@ -0,0 +1,295 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// searchIndex is the full-text index of an encrypted database. It holds the decrypted titles
+// and messages in an in-memory SQLite database, so they are never written to disk
+type searchIndex struct {
+	db     *sql.DB
+	mu     sync.RWMutex
+	titles map[string]string // Decrypted titles by conversation ID
+}
+
+// indexHit is a conversation matching a search of the in-memory index
+type indexHit struct {
+	SearchHit
+	score float64
+}
+
+func newSearchIndex() (*searchIndex, error) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		return nil, fmt.Errorf("error creating search index: %w", err)
+	}
+	// Every connection to :memory: opens a separate database, so keep a single one open
+	db.SetMaxOpenConns(1)
+	db.SetConnMaxLifetime(0)
+
+	_, err = db.Exec(`
+		CREATE VIRTUAL TABLE messages_fts USING fts5(
+			content,
+			message_id UNINDEXED,
+			conversation_id UNINDEXED,
+			tokenize = 'porter unicode61'
+		);
+
+		CREATE VIRTUAL TABLE conversations_fts USING fts5(
+			title,
+			conversation_id UNINDEXED,
+			tokenize = 'porter unicode61'
+		);
+	`)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error creating search index: %w", err)
+	}
+	return &searchIndex{db: db, titles: make(map[string]string)}, nil
+}
+
+func (ix *searchIndex) Close() error {
+	return ix.db.Close()
+}
+
+// addMessage indexes the decrypted content of a message
+func (ix *searchIndex) addMessage(id, conversationID, content string) error {
+	_, err := ix.db.Exec(`
+		INSERT INTO messages_fts (content, message_id, conversation_id)
+		VALUES (?, ?, ?)
+	`, content, id, conversationID)
+	if err != nil {
+		return fmt.Errorf("error indexing message: %w", err)
+	}
+	return nil
+}
+
+// setTitle indexes the decrypted title of a conversation, replacing the previous one
+func (ix *searchIndex) setTitle(conversationID, title string) error {
+	ix.mu.Lock()
+	ix.titles[conversationID] = title
+	ix.mu.Unlock()
+
+	if _, err := ix.db.Exec(`DELETE FROM conversations_fts WHERE conversation_id = ?`, conversationID); err != nil {
+		return fmt.Errorf("error indexing title: %w", err)
+	}
+	_, err := ix.db.Exec(`INSERT INTO conversations_fts (title, conversation_id) VALUES (?, ?)`, title, conversationID)
+	if err != nil {
+		return fmt.Errorf("error indexing title: %w", err)
+	}
+	return nil
+}
+
+// removeConversation drops a deleted conversation from the index
+func (ix *searchIndex) removeConversation(conversationID string) error {
+	ix.mu.Lock()
+	delete(ix.titles, conversationID)
+	ix.mu.Unlock()
+
+	if _, err := ix.db.Exec(`DELETE FROM conversations_fts WHERE conversation_id = ?`, conversationID); err != nil {
+		return fmt.Errorf("error removing conversation from search index: %w", err)
+	}
+	if _, err := ix.db.Exec(`DELETE FROM messages_fts WHERE conversation_id = ?`, conversationID); err != nil {
+		return fmt.Errorf("error removing conversation from search index: %w", err)
+	}
+	return nil
+}
+
+// search returns every conversation matching the FTS5 query match with its best hit
+func (ix *searchIndex) search(match string) ([]indexHit, error) {
+	rows, err := ix.db.Query(`
+		WITH message_hits AS (
+			SELECT conversation_id, message_id,
+				snippet(messages_fts, 0, ?, ?, '…', 12) AS snippet,
+				bm25(messages_fts) AS score
+			FROM messages_fts
+			WHERE messages_fts MATCH ?
+		),
+		title_hits AS (
+			SELECT conversation_id,
+				highlight(conversations_fts, 0, ?, ?) AS title,
+				bm25(conversations_fts) AS score
+			FROM conversations_fts
+			WHERE conversations_fts MATCH ?
+		),
+		best_messages AS (
+			SELECT conversation_id, message_id, snippet, score,
+				ROW_NUMBER() OVER (PARTITION BY conversation_id ORDER BY score) AS position
+			FROM message_hits
+		),
+		matches AS (
+			SELECT conversation_id FROM title_hits
+			UNION
+			SELECT conversation_id FROM message_hits
+		)
+		SELECT x.conversation_id, COALESCE(t.title, ''), COALESCE(m.message_id, ''), COALESCE(m.snippet, ''),
+			MIN(COALESCE(t.score * 2, 0), COALESCE(m.score, 0))
+		FROM matches x
+		LEFT JOIN title_hits t ON t.conversation_id = x.conversation_id
+		LEFT JOIN best_messages m ON m.conversation_id = x.conversation_id AND m.position = 1
+	`, HighlightStart, HighlightEnd, match, HighlightStart, HighlightEnd, match)
+	if err != nil {
+		return nil, fmt.Errorf("error searching conversations: %w", err)
+	}
+	defer rows.Close()
+
+	var hits []indexHit
+	for rows.Next() {
+		var hit indexHit
+		if err := rows.Scan(&hit.ConversationID, &hit.Title, &hit.MessageID, &hit.Snippet, &hit.score); err != nil {
+			return nil, fmt.Errorf("error scanning search result: %w", err)
+		}
+		if hit.Title == "" {
+			ix.mu.RLock()
+			hit.Title = ix.titles[hit.ConversationID]
+			ix.mu.RUnlock()
+		}
+		hits = append(hits, hit)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating search results: %w", err)
+	}
+	return hits, nil
+}
+
+// indexUpdates collects the changes a write transaction makes to the in-memory index, so
+// they are applied only once it commits and a rollback leaves the index as it was
+type indexUpdates struct {
+	index   *searchIndex // nil for an unencrypted database, whose triggers keep the index
+	updates []func(*searchIndex) error
+}
+
+func (db *DB) newIndexUpdates() *indexUpdates {
+	return &indexUpdates{index: db.index}
+}
+
+func (u *indexUpdates) addMessage(id, conversationID, content string) {
+	if u.index != nil {
+		u.updates = append(u.updates, func(ix *searchIndex) error { return ix.addMessage(id, conversationID, content) })
+	}
+}
+
+func (u *indexUpdates) setTitle(conversationID, title string) {
+	if u.index != nil {
+		u.updates = append(u.updates, func(ix *searchIndex) error { return ix.setTitle(conversationID, title) })
+	}
+}
+
+func (u *indexUpdates) removeConversation(conversationID string) {
+	if u.index != nil {
+		u.updates = append(u.updates, func(ix *searchIndex) error { return ix.removeConversation(conversationID) })
+	}
+}
+
+// apply updates the index after the transaction committed
+func (u *indexUpdates) apply() error {
+	for _, update := range u.updates {
+		if err := update(u.index); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// buildSearchIndex decrypts every title and message into index
+func (db *DB) buildSearchIndex(index *searchIndex) error {
+	conversations, err := db.Query(`SELECT id, title FROM conversations`)
+	if err != nil {
+		return fmt.Errorf("error indexing conversations: %w", err)
+	}
+	defer conversations.Close()
+	for conversations.Next() {
+		var id, title string
+		if err := conversations.Scan(&id, &title); err != nil {
+			return fmt.Errorf("error indexing conversations: %w", err)
+		}
+		if title, err = db.open(title, "title", id); err != nil {
+			return err
+		}
+		if err := index.setTitle(id, title); err != nil {
+			return err
+		}
+	}
+	if err := conversations.Err(); err != nil {
+		return fmt.Errorf("error indexing conversations: %w", err)
+	}
+
+	messages, err := db.Query(`SELECT id, conversation_id, content FROM messages`)
+	if err != nil {
+		return fmt.Errorf("error indexing messages: %w", err)
+	}
+	defer messages.Close()
+	for messages.Next() {
+		var id, conversationID, content string
+		if err := messages.Scan(&id, &conversationID, &content); err != nil {
+			return fmt.Errorf("error indexing messages: %w", err)
+		}
+		if content, err = db.open(content, "content", id); err != nil {
+			return err
+		}
+		if err := index.addMessage(id, conversationID, content); err != nil {
+			return err
+		}
+	}
+	if err := messages.Err(); err != nil {
+		return fmt.Errorf("error indexing messages: %w", err)
+	}
+	return nil
+}
+
+// searchEncrypted is SearchConversations for an encrypted database, using the in-memory index
+func (db *DB) searchEncrypted(match string, limit int) ([]SearchHit, error) {
+	if db.index == nil {
+		return nil, ErrLocked
+	}
+	hits, err := db.index.search(match)
+	if err != nil || len(hits) == 0 {
+		return nil, err
+	}
+
+	// Only conversations outside the trash are listed, most recently updated first on equal scores
+	rows, err := db.Query(`SELECT id, updated_at FROM conversations WHERE deleted_at IS NULL`)
+	if err != nil {
+		return nil, fmt.Errorf("error searching conversations: %w", err)
+	}
+	defer rows.Close()
+	updated := make(map[string]time.Time)
+	for rows.Next() {
+		var id string
+		var updatedAt time.Time
+		if err := rows.Scan(&id, &updatedAt); err != nil {
+			return nil, fmt.Errorf("error searching conversations: %w", err)
+		}
+		updated[id] = updatedAt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error searching conversations: %w", err)
+	}
+
+	live := hits[:0]
+	for _, hit := range hits {
+		if updatedAt, ok := updated[hit.ConversationID]; ok {
+			hit.UpdatedAt = updatedAt
+			live = append(live, hit)
+		}
+	}
+	sort.SliceStable(live, func(i, j int) bool {
+		if live[i].score != live[j].score {
+			return live[i].score < live[j].score
+		}
+		return live[i].UpdatedAt.After(live[j].UpdatedAt)
+	})
+
+	results := make([]SearchHit, 0, len(live))
+	for _, hit := range live {
+		if limit > 0 && len(results) == limit {
+			break
+		}
+		results = append(results, hit.SearchHit)
+	}
+	return results, nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/tedfulk/goatmeal/config"
+	"github.com/tedfulk/goatmeal/database"
+	"golang.org/x/term"
+)
+
+const (
+	// passphraseEnv holds the passphrase of an encrypted database
+	passphraseEnv = "GOATMEAL_PASSPHRASE"
+	// newPassphraseEnv holds the passphrase for encrypt and rekey, so they can run without a terminal
+	newPassphraseEnv = "GOATMEAL_NEW_PASSPHRASE"
+	// unlockAttempts is how often a typed passphrase may be wrong
+	unlockAttempts = 3
+)
+
+// unlock opens an encrypted database with the passphrase from $GOATMEAL_PASSPHRASE or the
+// passphrase command, or asks for it. With promptOnWrong a wrong passphrase from either
+// is followed by a prompt instead of failing
+func unlock(db *database.DB, cfg *config.Config, prompt string, promptOnWrong bool) error {
+	if !db.Locked() {
+		return nil
+	}
+
+	passphrase, ok, err := configuredPassphrase(cfg)
+	if err != nil {
+		return err
+	}
+	if ok {
+		err := db.Unlock(passphrase)
+		if err == nil || !promptOnWrong || !errors.Is(err, database.ErrWrongPassphrase) {
+			return err
+		}
+	}
+
+	for attempt := 1; ; attempt++ {
+		passphrase, err := promptPassphrase(prompt, passphraseEnv)
+		if err != nil {
+			return err
+		}
+		err = db.Unlock(passphrase)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, database.ErrWrongPassphrase) || attempt == unlockAttempts {
+			return err
+		}
+		fmt.Fprintln(os.Stderr, "Wrong passphrase, try again")
+	}
+}
+
+// configuredPassphrase returns the passphrase from $GOATMEAL_PASSPHRASE or the passphrase command
+// ok is false when neither is set
+func configuredPassphrase(cfg *config.Config) (passphrase string, ok bool, err error) {
+	if passphrase, ok := os.LookupEnv(passphraseEnv); ok {
+		return passphrase, true, nil
+	}
+	command := cfg.Settings.Encryption.PassphraseCommand
+	if command == "" {
+		return "", false, nil
+	}
+
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "windows":
+		cmd = exec.Command("cmd", "/C", command)
+	default:
+		cmd = exec.Command("sh", "-c", command)
+	}
+	// Password managers may ask for their own passphrase on the terminal
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", false, fmt.Errorf("error running passphrase command: %w", err)
+	}
+	return strings.TrimRight(string(out), "\r\n"), true, nil
+}
+
+// newPassphrase returns the passphrase for encrypt and rekey from $GOATMEAL_NEW_PASSPHRASE,
+// or asks for it twice
+func newPassphrase() (string, error) {
+	if passphrase, ok := os.LookupEnv(newPassphraseEnv); ok {
+		if passphrase == "" {
+			return "", fmt.Errorf("$%s is empty", newPassphraseEnv)
+		}
+		return passphrase, nil
+	}
+
+	passphrase, err := promptPassphrase("New passphrase", newPassphraseEnv)
+	if err != nil {
+		return "", err
+	}
+	if passphrase == "" {
+		return "", errors.New("the passphrase can't be empty")
+	}
+	repeated, err := promptPassphrase("Repeat the passphrase", newPassphraseEnv)
+	if err != nil {
+		return "", err
+	}
+	if repeated != passphrase {
+		return "", errors.New("the passphrases don't match")
+	}
+	return passphrase, nil
+}
+
+// promptPassphrase reads a passphrase from the terminal without echoing it
+// Without a terminal the error points to env, the variable the passphrase can be set in instead
+func promptPassphrase(prompt, env string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("can't ask for the passphrase without a terminal, set $%s", env)
+	}
+	fmt.Fprintf(os.Stderr, "%s: ", prompt)
+	passphrase, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("error reading passphrase: %w", err)
+	}
+	return string(passphrase), nil
+}
@@ -1,12 +1,14 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/tedfulk/goatmeal/config"
 	"github.com/tedfulk/goatmeal/database"
+	"github.com/tedfulk/goatmeal/services/search"
 	"github.com/tedfulk/goatmeal/ui"
 	"github.com/tedfulk/goatmeal/ui/setup"
 )
@@ -18,6 +20,11 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Commands run without the chat UI
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(cfg, os.Args[1:]))
+	}
+
 	// Check if setup wizard needs to run
 	if cfg.CurrentModel == "" {
 		wizard := setup.NewWizard(cfg)
@@ -42,8 +49,7 @@ func main() {
 	}
 
 	// Initialize database
-	dbPath := os.ExpandEnv("$HOME/.config/goatmeal/goatmeal.db")
-	db, err := database.NewDB(dbPath)
+	db, err := openDB(cfg)
 	if err != nil {
 		fmt.Printf("Error initializing database: %v\n", err)
 		os.Exit(1)
@@ -51,12 +57,27 @@ func main() {
 	defer db.Close()
 
 	// Clean up old conversations
-	if err := db.CleanupOldConversations(cfg.Settings.ConversationRetention); err != nil {
+	var notice string
+	if archiveDir, err := retentionArchiveDir(cfg.Settings.ArchiveExpired); err != nil {
+		fmt.Printf("Error cleaning up old conversations: %v\n", err)
+	} else if _, err := db.CleanupOldConversations(cfg.Settings.ConversationRetention, archiveDir); errors.Is(err, database.ErrArchiveEncrypted) {
+		notice = "Old conversations were kept, archives can't be encrypted. Run goatmeal cleanup to delete them"
+	} else if err != nil {
 		fmt.Printf("Error cleaning up old conversations: %v\n", err)
 	}
 
+	// Clean up expired search results
+	if ttl := search.CacheTTL(cfg.Settings.Search.CacheTTL); ttl > 0 {
+		if err := db.CleanupSearchCache(ttl); err != nil {
+			fmt.Printf("Error cleaning up search cache: %v\n", err)
+		}
+	}
+
 	// Initialize UI
 	app := ui.NewApp(cfg, db)
+	if notice != "" {
+		app.SetStartupNotice(notice)
+	}
 	p := tea.NewProgram(app, tea.WithAltScreen())
 	if _, err := p.Run(); err != nil {
 		fmt.Printf("Error running application: %v\n", err)
@@ -0,0 +1,169 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/tedfulk/goatmeal/database"
+)
+
+// formatVersion is the version of the backup layout this build writes and reads
+const formatVersion = 1
+
+// Names of the files in a backup
+const (
+	manifestName = "manifest.json"
+	databaseName = "goatmeal.db"
+	configName   = "config.yaml"
+	archiveDir   = "archive" // Retention archives written by cleanup --archive
+)
+
+// Manifest describes a backup
+type Manifest struct {
+	Version       int       `json:"version"`
+	CreatedAt     time.Time `json:"created_at"`
+	SchemaVersion int       `json:"schema_version"`
+	KeysRedacted  bool      `json:"keys_redacted"`
+	HasConfig     bool      `json:"has_config"`
+	Archives      int       `json:"archives"`
+}
+
+// Options control what a backup contains
+type Options struct {
+	IncludeKeys bool // Keep the API keys in config.yaml, they're blanked out otherwise
+}
+
+// Create writes a backup of the database, config.yaml and retention archives in configDir to path,
+// a gzipped tar file. The database is copied with VACUUM INTO, so goatmeal can keep running
+func Create(db *database.DB, configDir, path string, opts Options) (Manifest, error) {
+	manifest := Manifest{
+		Version:       formatVersion,
+		CreatedAt:     time.Now(),
+		SchemaVersion: database.SchemaVersion(),
+		KeysRedacted:  !opts.IncludeKeys,
+	}
+
+	tmpDir, err := os.MkdirTemp("", "goatmeal-backup-")
+	if err != nil {
+		return manifest, fmt.Errorf("error creating temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	snapshot := filepath.Join(tmpDir, databaseName)
+	if err := db.Snapshot(snapshot); err != nil {
+		return manifest, err
+	}
+
+	config, err := os.ReadFile(filepath.Join(configDir, configName))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return manifest, fmt.Errorf("error reading config: %w", err)
+	}
+	if config != nil {
+		manifest.HasConfig = true
+		if !opts.IncludeKeys {
+			if config, err = redactKeys(config); err != nil {
+				return manifest, err
+			}
+		}
+	}
+
+	archives, err := filepath.Glob(filepath.Join(configDir, archiveDir, "*.jsonl.gz"))
+	if err != nil {
+		return manifest, fmt.Errorf("error listing archives: %w", err)
+	}
+	manifest.Archives = len(archives)
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return manifest, fmt.Errorf("error creating backup: %w", err)
+	}
+	if err := writeBackup(f, manifest, snapshot, config, archives); err != nil {
+		f.Close()
+		os.Remove(path)
+		return manifest, fmt.Errorf("error writing backup: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(path)
+		return manifest, fmt.Errorf("error writing backup: %w", err)
+	}
+	return manifest, nil
+}
+
+// writeBackup writes the files of a backup to w as a gzipped tar
+func writeBackup(w io.Writer, manifest Manifest, snapshot string, config []byte, archives []string) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeEntry(tw, manifestName, data, manifest.CreatedAt); err != nil {
+		return err
+	}
+	if err := copyEntry(tw, databaseName, snapshot); err != nil {
+		return err
+	}
+	if config != nil {
+		if err := writeEntry(tw, configName, config, manifest.CreatedAt); err != nil {
+			return err
+		}
+	}
+	for _, archive := range archives {
+		if err := copyEntry(tw, archiveDir+"/"+filepath.Base(archive), archive); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// writeEntry adds a file with data to the backup
+func writeEntry(tw *tar.Writer, name string, data []byte, modTime time.Time) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0600,
+		Size:    int64(len(data)),
+		ModTime: modTime,
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+// copyEntry adds the file at path to the backup
+func copyEntry(tw *tar.Writer, name, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0600,
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
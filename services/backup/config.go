@@ -0,0 +1,78 @@
+package backup
+
+import (
+	"bytes"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// apiKeysField is the config.yaml field holding the API keys
+const apiKeysField = "api_keys"
+
+// redactKeys blanks out every API key in a config.yaml, keeping the rest of the file as it is
+func redactKeys(config []byte) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(config, &doc); err != nil {
+		return nil, fmt.Errorf("error parsing config: %w", err)
+	}
+	keys := field(&doc, apiKeysField)
+	if keys == nil || keys.Kind != yaml.MappingNode {
+		return config, nil
+	}
+	for i := 1; i < len(keys.Content); i += 2 {
+		keys.Content[i].SetString("")
+	}
+	return marshal(&doc)
+}
+
+// keepKeys copies the API keys of the current config.yaml into a restored one whose keys were redacted
+func keepKeys(restored, current []byte) ([]byte, error) {
+	var doc, currentDoc yaml.Node
+	if err := yaml.Unmarshal(restored, &doc); err != nil {
+		return nil, fmt.Errorf("error parsing config from backup: %w", err)
+	}
+	if err := yaml.Unmarshal(current, &currentDoc); err != nil {
+		return nil, fmt.Errorf("error parsing config: %w", err)
+	}
+
+	currentKeys := field(&currentDoc, apiKeysField)
+	if currentKeys == nil || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return restored, nil
+	}
+	if keys := field(&doc, apiKeysField); keys != nil {
+		*keys = *currentKeys
+	} else {
+		root := doc.Content[0]
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: apiKeysField}, currentKeys)
+	}
+	return marshal(&doc)
+}
+
+// field returns the value of a top level field of a YAML document, or nil if it's missing
+func field(doc *yaml.Node, name string) *yaml.Node {
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil
+	}
+	root := doc.Content[0]
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == name {
+			return root.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// marshal writes a YAML document indented like the config files goatmeal writes
+func marshal(doc *yaml.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return nil, fmt.Errorf("error writing config: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("error writing config: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,260 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/tedfulk/goatmeal/database"
+)
+
+// RestoreOptions control how a backup is restored
+type RestoreOptions struct {
+	ReplaceConfig bool                     // Replace an existing config.yaml, which is kept otherwise
+	Unlock        func(*database.DB) error // Unlocks the backed up database when it's encrypted
+}
+
+// Config outcomes of a restore
+const (
+	ConfigNone     = ""         // The backup has no config.yaml
+	ConfigRestored = "restored" // There was no config.yaml, the one from the backup was written
+	ConfigKept     = "kept"     // The existing config.yaml was left alone
+	ConfigReplaced = "replaced" // The existing config.yaml was moved aside and replaced
+)
+
+// RestoreResult reports what a restore changed
+type RestoreResult struct {
+	Manifest     Manifest
+	Added        int    // Conversations that weren't in the database
+	Updated      int    // Existing conversations that got messages from the backup
+	Messages     int    // Messages added to existing conversations
+	Unchanged    int    // Conversations already up to date
+	Archives     int    // Retention archives copied
+	Config       string // One of the Config outcomes
+	ConfigBackup string // Where the replaced config.yaml was moved to
+}
+
+// Restore merges a backup written by Create into db and configDir
+// Conversations are matched by ID, so restoring never removes or overwrites anything
+// in the database. Existing conversations only gain the messages and tags they're missing
+func Restore(db *database.DB, configDir, backupPath string, opts RestoreOptions) (RestoreResult, error) {
+	var result RestoreResult
+
+	tmpDir, err := os.MkdirTemp("", "goatmeal-restore-")
+	if err != nil {
+		return result, fmt.Errorf("error creating temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if err := extract(backupPath, tmpDir); err != nil {
+		return result, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(tmpDir, manifestName))
+	if err != nil {
+		return result, fmt.Errorf("%s is not a goatmeal backup", backupPath)
+	}
+	if err := json.Unmarshal(data, &result.Manifest); err != nil {
+		return result, fmt.Errorf("error reading backup manifest: %w", err)
+	}
+	if result.Manifest.Version > formatVersion {
+		return result, fmt.Errorf("backup was made by a newer version of goatmeal, please upgrade goatmeal")
+	}
+
+	if err := mergeDatabase(db, filepath.Join(tmpDir, databaseName), opts, &result); err != nil {
+		return result, err
+	}
+	if err := restoreArchives(filepath.Join(tmpDir, archiveDir), filepath.Join(configDir, archiveDir), &result); err != nil {
+		return result, err
+	}
+	if err := restoreConfig(filepath.Join(tmpDir, configName), configDir, opts, &result); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// extract unpacks the known files of a backup into dir
+func extract(backupPath, dir string) error {
+	f, err := os.Open(backupPath)
+	if err != nil {
+		return fmt.Errorf("error opening backup: %w", err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("%s is not a goatmeal backup: %w", backupPath, err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error reading backup: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg || !known(header.Name) {
+			continue
+		}
+
+		target := filepath.Join(dir, filepath.FromSlash(header.Name))
+		if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+			return fmt.Errorf("error extracting backup: %w", err)
+		}
+		out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+		if err != nil {
+			return fmt.Errorf("error extracting backup: %w", err)
+		}
+		_, err = io.Copy(out, tr)
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("error extracting backup: %w", err)
+		}
+	}
+}
+
+// known reports whether name is one of the files a backup contains
+// Anything else is skipped, so a crafted backup can't write outside the extract directory
+func known(name string) bool {
+	switch name {
+	case manifestName, databaseName, configName:
+		return true
+	}
+	dir, file := path.Split(name)
+	return dir == archiveDir+"/" && strings.HasSuffix(file, ".jsonl.gz") && !strings.HasPrefix(file, ".")
+}
+
+// mergeDatabase merges every conversation in the backed up database, including the trash, into db
+func mergeDatabase(db *database.DB, snapshot string, opts RestoreOptions, result *RestoreResult) error {
+	if _, err := os.Stat(snapshot); err != nil {
+		return fmt.Errorf("backup has no database")
+	}
+
+	// Opening the snapshot upgrades it when it was made by an older goatmeal
+	backup, err := database.NewDB(snapshot)
+	if err != nil {
+		return fmt.Errorf("error opening backed up database: %w", err)
+	}
+	defer backup.Close()
+
+	if backup.Locked() {
+		if opts.Unlock == nil {
+			return database.ErrLocked
+		}
+		if err := opts.Unlock(backup); err != nil {
+			return fmt.Errorf("error unlocking backed up database: %w", err)
+		}
+	}
+
+	ids, err := backup.ConversationIDs()
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		conv, err := backup.ExportConversation(id, true)
+		if err != nil {
+			return err
+		}
+		added, messages, err := db.MergeConversation(conv)
+		if err != nil {
+			return fmt.Errorf("error restoring %q: %w", conv.Title, err)
+		}
+		switch {
+		case added:
+			result.Added++
+		case messages > 0:
+			result.Updated++
+			result.Messages += messages
+		default:
+			result.Unchanged++
+		}
+	}
+	return nil
+}
+
+// restoreArchives copies the retention archives that don't exist in dst yet
+func restoreArchives(src, dst string, result *RestoreResult) error {
+	archives, err := filepath.Glob(filepath.Join(src, "*.jsonl.gz"))
+	if err != nil || len(archives) == 0 {
+		return err
+	}
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return fmt.Errorf("error creating archive directory: %w", err)
+	}
+
+	for _, archive := range archives {
+		target := filepath.Join(dst, filepath.Base(archive))
+		if _, err := os.Stat(target); err == nil {
+			continue
+		}
+		data, err := os.ReadFile(archive)
+		if err != nil {
+			return fmt.Errorf("error restoring archive: %w", err)
+		}
+		if err := os.WriteFile(target, data, 0644); err != nil {
+			return fmt.Errorf("error restoring archive: %w", err)
+		}
+		result.Archives++
+	}
+	return nil
+}
+
+// restoreConfig writes the backed up config.yaml when there is none, or when replacing it
+// A replaced config is moved aside, and keeps its API keys when the backup's were redacted
+func restoreConfig(src, configDir string, opts RestoreOptions, result *RestoreResult) error {
+	restored, err := os.ReadFile(src)
+	if errors.Is(err, os.ErrNotExist) {
+		result.Config = ConfigNone
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading config from backup: %w", err)
+	}
+
+	target := filepath.Join(configDir, configName)
+	current, err := os.ReadFile(target)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("error reading config: %w", err)
+	}
+
+	if current != nil {
+		if !opts.ReplaceConfig {
+			result.Config = ConfigKept
+			return nil
+		}
+		if result.Manifest.KeysRedacted {
+			if restored, err = keepKeys(restored, current); err != nil {
+				return err
+			}
+		}
+		result.ConfigBackup = fmt.Sprintf("%s.%s.bak", target, time.Now().Format("20060102-150405"))
+		if err := os.Rename(target, result.ConfigBackup); err != nil {
+			return fmt.Errorf("error moving config aside: %w", err)
+		}
+		result.Config = ConfigReplaced
+	} else {
+		result.Config = ConfigRestored
+	}
+
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		return fmt.Errorf("error creating config directory: %w", err)
+	}
+	if err := os.WriteFile(target, restored, 0600); err != nil {
+		return fmt.Errorf("error writing config: %w", err)
+	}
+	return nil
+}
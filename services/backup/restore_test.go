@@ -0,0 +1,110 @@
+package backup
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/tedfulk/goatmeal/database"
+)
+
+func openTestDB(t *testing.T, dir string) *database.DB {
+	t.Helper()
+	db, err := database.NewDB(filepath.Join(dir, "goatmeal.db"))
+	if err != nil {
+		t.Fatalf("error opening database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func importConversations(t *testing.T, db *database.DB, from, to int) {
+	t.Helper()
+	for i := from; i < to; i++ {
+		created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(i) * time.Minute)
+		id := fmt.Sprintf("conv-%04d", i)
+		conv := &database.Conversation{
+			ID:           id,
+			Title:        fmt.Sprintf("Conversation %d", i),
+			Provider:     "openai",
+			Model:        "gpt-4o",
+			CreatedAt:    created,
+			UpdatedAt:    created,
+			ActiveLeafID: id + "-msg",
+			Tags:         []string{"restored"},
+			Messages: []database.Message{
+				{ID: id + "-msg", Role: "user", Content: "hello", CreatedAt: created},
+			},
+		}
+		if _, err := db.ImportConversation(conv); err != nil {
+			t.Fatalf("error importing conversation %d: %v", i, err)
+		}
+	}
+}
+
+func TestRestore(t *testing.T) {
+	tests := []struct {
+		name     string
+		backedUp int // Conversations in the backup
+		existing int // Of those, how many the database already has
+		trashed  int // Of those, how many are in the backup's trash
+	}{
+		{"small backup", 10, 0, 2},
+		{"more conversations than addListDetails filters by ID", 520, 0, 20},
+		{"merge into existing conversations", 520, 300, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			source := openTestDB(t, dir)
+			importConversations(t, source, 0, tt.backedUp)
+			for i := 0; i < tt.trashed; i++ {
+				if err := source.DeleteConversation(fmt.Sprintf("conv-%04d", i)); err != nil {
+					t.Fatalf("error deleting conversation: %v", err)
+				}
+			}
+
+			backupPath := filepath.Join(dir, "goatmeal.backup")
+			if _, err := Create(source, dir, backupPath, Options{}); err != nil {
+				t.Fatalf("error creating backup: %v", err)
+			}
+
+			targetDir := t.TempDir()
+			target := openTestDB(t, targetDir)
+			importConversations(t, target, 0, tt.existing)
+
+			result, err := Restore(target, targetDir, backupPath, RestoreOptions{})
+			if err != nil {
+				t.Fatalf("error restoring backup: %v", err)
+			}
+			if result.Added != tt.backedUp-tt.existing || result.Unchanged != tt.existing || result.Updated != 0 {
+				t.Errorf("got %d added, %d unchanged and %d updated, want %d, %d and 0",
+					result.Added, result.Unchanged, result.Updated, tt.backedUp-tt.existing, tt.existing)
+			}
+
+			ids, err := target.ConversationIDs()
+			if err != nil {
+				t.Fatalf("error listing conversations: %v", err)
+			}
+			if len(ids) != tt.backedUp {
+				t.Errorf("got %d conversations, want %d", len(ids), tt.backedUp)
+			}
+			deleted, err := target.GetDeletedConversations()
+			if err != nil {
+				t.Fatalf("error listing the trash: %v", err)
+			}
+			if len(deleted) != tt.trashed {
+				t.Errorf("got %d conversations in the trash, want %d", len(deleted), tt.trashed)
+			}
+			tagged, err := target.GetTaggedConversations([]string{"restored"})
+			if err != nil {
+				t.Fatalf("error listing tagged conversations: %v", err)
+			}
+			if len(tagged) != tt.backedUp-tt.trashed {
+				t.Errorf("got %d tagged conversations, want %d", len(tagged), tt.backedUp-tt.trashed)
+			}
+		})
+	}
+}
@@ -0,0 +1,218 @@
+package export
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/tedfulk/goatmeal/database"
+	"github.com/tedfulk/goatmeal/services/search"
+	"github.com/tedfulk/goatmeal/utils/models"
+)
+
+// Format is a file format conversations can be exported to
+type Format string
+
+const (
+	Markdown Format = "markdown"
+	HTML     Format = "html"
+	Text     Format = "text"
+	JSONL    Format = "jsonl" // OpenAI chat format, one conversation per line
+	JSON     Format = "json"  // goatmeal's own format, which can be imported again
+)
+
+// Formats lists the export formats in the order they're offered
+var Formats = []Format{Markdown, HTML, Text, JSONL, JSON}
+
+// Extension returns the file extension for the format, without the dot
+func (f Format) Extension() string {
+	switch f {
+	case Markdown:
+		return "md"
+	case Text:
+		return "txt"
+	default:
+		return string(f)
+	}
+}
+
+// Options control how conversations are written
+type Options struct {
+	Username      string            // Shown for user messages
+	SystemPrompts map[string]string // System prompt text by title, added to JSONL exports
+	Colors        Colors            // Used by HTML exports
+}
+
+// Colors style HTML exports, as CSS colors
+// Empty colors fall back to a dark default
+type Colors struct {
+	Background string
+	Text       string
+	Heading    string
+	Muted      string
+	User       string
+	Assistant  string
+	Accent     string
+	Border     string
+}
+
+// DefaultDir is where exports go when no export directory is configured
+const DefaultDir = "~/Downloads"
+
+// Dir returns the export directory for the configured setting, creating it if needed
+func Dir(configured string) (string, error) {
+	dir := strings.TrimSpace(configured)
+	if dir == "" {
+		dir = DefaultDir
+	}
+	if dir == "~" || strings.HasPrefix(dir, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("error getting home directory: %w", err)
+		}
+		dir = filepath.Join(home, strings.TrimPrefix(dir, "~"))
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("error creating export directory: %w", err)
+	}
+	return dir, nil
+}
+
+// Encode writes a conversation in format to w
+func Encode(w io.Writer, format Format, conv *database.Conversation, opts Options) error {
+	switch format {
+	case Markdown:
+		return writeMarkdown(w, conv, opts)
+	case HTML:
+		return writeHTML(w, conv, opts)
+	case Text:
+		return writeText(w, conv, opts)
+	case JSONL:
+		return writeJSONL(w, conv, opts)
+	case JSON:
+		return writeJSON(w, conv)
+	}
+	return fmt.Errorf("unknown export format %q", format)
+}
+
+// WriteFiles exports conversations to dir and returns the files written
+// JSONL puts every conversation in a single file, the other formats write a file per conversation
+func WriteFiles(dir string, format Format, conversations []*database.Conversation, opts Options) ([]string, error) {
+	timestamp := time.Now().Format("2006-01-02-150405")
+
+	if format == JSONL && len(conversations) > 1 {
+		path, err := writeFile(dir, "conversations-"+timestamp, format, func(w io.Writer) error {
+			for _, conv := range conversations {
+				if err := Encode(w, format, conv, opts); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return []string{path}, nil
+	}
+
+	var paths []string
+	for _, conv := range conversations {
+		name := fmt.Sprintf("conversation-%s-%s", sanitize(conv.Title), timestamp)
+		path, err := writeFile(dir, name, format, func(w io.Writer) error {
+			return Encode(w, format, conv, opts)
+		})
+		if err != nil {
+			return paths, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// writeFile creates a new file named name in dir and fills it with write
+// A number is added to the name when the file already exists, and a partly written file is removed
+func writeFile(dir, name string, format Format, write func(io.Writer) error) (string, error) {
+	var f *os.File
+	var path string
+	for i := 1; ; i++ {
+		path = filepath.Join(dir, name+"."+format.Extension())
+		if i > 1 {
+			path = filepath.Join(dir, fmt.Sprintf("%s-%d.%s", name, i, format.Extension()))
+		}
+		var err error
+		f, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return "", fmt.Errorf("error creating export file: %w", err)
+		}
+	}
+
+	w := bufio.NewWriter(f)
+	err := write(w)
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return "", fmt.Errorf("error writing export file: %w", err)
+	}
+	return path, nil
+}
+
+// sanitize turns a title into something safe to use in a file name
+func sanitize(title string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) || r == '-' || r == '_' {
+			return r
+		}
+		return '-'
+	}, title)
+}
+
+// speaker returns the name shown above a message
+func speaker(conv *database.Conversation, msg database.Message, opts Options) string {
+	switch {
+	case msg.Role == "user":
+		if opts.Username != "" {
+			return opts.Username
+		}
+		return "User"
+	case msg.Role == "search":
+		return search.DisplayName(conv.Provider)
+	case msg.Model != "":
+		return models.StripModelsPrefix(msg.Model)
+	case search.IsBackend(conv.Provider):
+		return search.DisplayName(conv.Provider)
+	case conv.Model != "":
+		return models.StripModelsPrefix(conv.Model)
+	}
+	return "Assistant"
+}
+
+// summary describes the model, creation date and tags of a conversation on one line
+func summary(conv *database.Conversation) string {
+	parts := []string{conv.CreatedAt.Format("January 2, 2006")}
+	if conv.Model != "" {
+		parts = append(parts, conv.Provider+"/"+models.StripModelsPrefix(conv.Model))
+	}
+	if len(conv.Tags) > 0 {
+		parts = append(parts, "#"+strings.Join(conv.Tags, " #"))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// timestamp formats the time of a message
+func timestamp(t time.Time) string {
+	return t.Format("Jan 2, 2006 15:04")
+}
@@ -0,0 +1,118 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+
+	"github.com/tedfulk/goatmeal/database"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// markdown renders message content for HTML exports
+// Raw HTML in messages is left out, so an export never runs scripts from a reply
+var markdown = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+// defaultColors fill in the colors a theme leaves empty
+var defaultColors = Colors{
+	Background: "#1e1e2e",
+	Text:       "#cdd6f4",
+	Heading:    "#cba6f7",
+	Muted:      "#7f849c",
+	User:       "#89b4fa",
+	Assistant:  "#a6e3a1",
+	Accent:     "#f5c2e7",
+	Border:     "#45475a",
+}
+
+// htmlMessage is a message as shown in an HTML export
+type htmlMessage struct {
+	Role    string
+	Speaker string
+	Time    string
+	Content template.HTML
+}
+
+var htmlTemplate = template.Must(template.New("conversation").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { background: {{.Colors.Background}}; color: {{.Colors.Text}}; font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif; line-height: 1.6; max-width: 52rem; margin: 2rem auto; padding: 0 1rem; }
+h1 { color: {{.Colors.Heading}}; margin-bottom: 0; }
+.summary, time { color: {{.Colors.Muted}}; font-size: 0.85rem; }
+.message { border: 1px solid {{.Colors.Border}}; border-radius: 8px; padding: 0.5rem 1rem; margin: 1rem 0; }
+.speaker { font-weight: bold; }
+.user .speaker { color: {{.Colors.User}}; }
+.assistant .speaker, .search .speaker { color: {{.Colors.Assistant}}; }
+a { color: {{.Colors.Accent}}; }
+pre, code { font-family: ui-monospace, SFMono-Regular, Menlo, Consolas, monospace; font-size: 0.9em; }
+pre { border: 1px solid {{.Colors.Border}}; border-radius: 6px; padding: 0.75rem; overflow-x: auto; }
+:not(pre) > code { border: 1px solid {{.Colors.Border}}; border-radius: 4px; padding: 0 0.25rem; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid {{.Colors.Border}}; padding: 0.25rem 0.5rem; }
+blockquote { border-left: 3px solid {{.Colors.Border}}; margin-left: 0; padding-left: 1rem; color: {{.Colors.Muted}}; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="summary">{{.Summary}}</p>
+{{range .Messages}}<section class="message {{.Role}}">
+<p><span class="speaker">{{.Speaker}}</span> <time>{{.Time}}</time></p>
+{{.Content}}</section>
+{{end}}</body>
+</html>
+`))
+
+// writeHTML writes a conversation as a self-contained HTML page styled with opts.Colors
+func writeHTML(w io.Writer, conv *database.Conversation, opts Options) error {
+	messages := make([]htmlMessage, len(conv.Messages))
+	for i, msg := range conv.Messages {
+		var content bytes.Buffer
+		if err := markdown.Convert([]byte(msg.Content), &content); err != nil {
+			return fmt.Errorf("error rendering message: %w", err)
+		}
+		messages[i] = htmlMessage{
+			Role:    msg.Role,
+			Speaker: speaker(conv, msg, opts),
+			Time:    timestamp(msg.CreatedAt),
+			Content: template.HTML(content.String()),
+		}
+	}
+
+	return htmlTemplate.Execute(w, struct {
+		Title    string
+		Summary  string
+		Colors   Colors
+		Messages []htmlMessage
+	}{
+		Title:    conv.Title,
+		Summary:  summary(conv),
+		Colors:   withDefaults(opts.Colors),
+		Messages: messages,
+	})
+}
+
+// withDefaults returns colors with the empty ones taken from defaultColors
+func withDefaults(c Colors) Colors {
+	pick := func(color, fallback string) string {
+		if color == "" {
+			return fallback
+		}
+		return color
+	}
+	return Colors{
+		Background: pick(c.Background, defaultColors.Background),
+		Text:       pick(c.Text, defaultColors.Text),
+		Heading:    pick(c.Heading, defaultColors.Heading),
+		Muted:      pick(c.Muted, defaultColors.Muted),
+		User:       pick(c.User, defaultColors.User),
+		Assistant:  pick(c.Assistant, defaultColors.Assistant),
+		Accent:     pick(c.Accent, defaultColors.Accent),
+		Border:     pick(c.Border, defaultColors.Border),
+	}
+}
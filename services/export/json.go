@@ -0,0 +1,53 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/tedfulk/goatmeal/database"
+)
+
+// chatMessage is a message in the OpenAI chat format
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// writeJSONL writes a conversation as a line of OpenAI chat messages, as used for fine-tuning
+// The system prompt is included when its text is known. Search answers count as assistant messages
+func writeJSONL(w io.Writer, conv *database.Conversation, opts Options) error {
+	var messages []chatMessage
+	for _, msg := range conv.Messages {
+		if msg.SystemPrompt != "" && opts.SystemPrompts[msg.SystemPrompt] != "" {
+			messages = append(messages, chatMessage{Role: "system", Content: opts.SystemPrompts[msg.SystemPrompt]})
+			break
+		}
+	}
+	for _, msg := range conv.Messages {
+		role := "assistant"
+		if msg.Role == "user" {
+			role = "user"
+		}
+		messages = append(messages, chatMessage{Role: role, Content: msg.Content})
+	}
+
+	data, err := json.Marshal(struct {
+		Messages []chatMessage `json:"messages"`
+	}{messages})
+	if err != nil {
+		return fmt.Errorf("error marshaling JSON: %w", err)
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// writeJSON writes a conversation in goatmeal's export format
+func writeJSON(w io.Writer, conv *database.Conversation) error {
+	data, err := json.MarshalIndent(database.NewExportedConversation(conv), "", "    ")
+	if err != nil {
+		return fmt.Errorf("error marshaling JSON: %w", err)
+	}
+	_, err = w.Write(data)
+	return err
+}
@@ -0,0 +1,66 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/tedfulk/goatmeal/database"
+)
+
+// writeMarkdown writes a conversation as Markdown with a heading for each message
+func writeMarkdown(w io.Writer, conv *database.Conversation, opts Options) error {
+	if _, err := fmt.Fprintf(w, "# %s\n\n_%s_\n", conv.Title, summary(conv)); err != nil {
+		return err
+	}
+	for _, msg := range conv.Messages {
+		_, err := fmt.Fprintf(w, "\n## %s\n\n_%s_\n\n%s\n",
+			speaker(conv, msg, opts),
+			timestamp(msg.CreatedAt),
+			closeFences(strings.TrimSpace(msg.Content)))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// closeFences closes a code fence left open at the end of content
+// so it doesn't swallow the headings of the messages after it
+func closeFences(content string) string {
+	var fence string
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		if len(line)-len(trimmed) > 3 {
+			continue
+		}
+		marker := fenceMarker(trimmed)
+		if marker == "" {
+			continue
+		}
+		if fence == "" {
+			fence = marker
+		} else if marker[0] == fence[0] && len(marker) >= len(fence) && strings.TrimSpace(trimmed[len(marker):]) == "" {
+			fence = ""
+		}
+	}
+	if fence == "" {
+		return content
+	}
+	return content + "\n" + fence
+}
+
+// fenceMarker returns the run of three or more backticks or tildes a line starts with
+func fenceMarker(line string) string {
+	if line == "" || (line[0] != '`' && line[0] != '~') {
+		return ""
+	}
+	n := 0
+	for n < len(line) && line[n] == line[0] {
+		n++
+	}
+	if n < 3 {
+		return ""
+	}
+	return line[:n]
+}
@@ -0,0 +1,30 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/tedfulk/goatmeal/database"
+)
+
+// writeText writes a conversation as plain text, each message under its speaker and time
+func writeText(w io.Writer, conv *database.Conversation, opts Options) error {
+	_, err := fmt.Fprintf(w, "%s\n%s\n%s\n",
+		conv.Title,
+		strings.Repeat("=", len([]rune(conv.Title))),
+		summary(conv))
+	if err != nil {
+		return err
+	}
+	for _, msg := range conv.Messages {
+		_, err := fmt.Fprintf(w, "\n%s (%s):\n%s\n",
+			speaker(conv, msg, opts),
+			timestamp(msg.CreatedAt),
+			strings.TrimSpace(msg.Content))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+// Package history keeps conversation history within a model's context window
+package history
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+const (
+	// defaultContextWindow is used when the model's context window is unknown
+	defaultContextWindow = 8192
+
+	// defaultReserveTokens is the space left free for the model's reply
+	defaultReserveTokens = 1024
+
+	// summaryPrefix introduces the rolling summary in the prompt
+	summaryPrefix = "Summary of earlier conversation: "
+)
+
+// Turn represents a single user or assistant entry in the conversation history
+type Turn struct {
+	Role    string // "user", "assistant" or "search"
+	Content string
+}
+
+// Summarizer condenses older turns, together with any previous summary, into a new summary
+type Summarizer func(ctx context.Context, previousSummary string, turns []Turn) (string, error)
+
+// Result describes the prompt produced by the budgeter
+type Result struct {
+	Prompt          string // History formatted for the provider
+	Summary         string // Rolling summary covering the first SummarizedTurns turns
+	SummarizedTurns int    // Number of leading turns covered by Summary
+	OmittedTurns    int    // Number of leading turns not sent verbatim
+	Summarized      bool   // Whether a new summary was generated for this prompt
+}
+
+// Truncated reports whether any turns were left out of the prompt
+func (r Result) Truncated() bool {
+	return r.OmittedTurns > 0
+}
+
+// Budgeter fits conversation history into a context window
+type Budgeter struct {
+	contextWindow int
+	reserveTokens int
+	summarizer    Summarizer
+}
+
+// NewBudgeter creates a budgeter for the given model
+// A contextWindow of 0 looks the window up from the model name, and a nil
+// summarizer drops older turns instead of summarizing them
+func NewBudgeter(model string, contextWindow, reserveTokens int, summarizer Summarizer) *Budgeter {
+	if contextWindow <= 0 {
+		contextWindow = ContextWindow(model)
+	}
+	if reserveTokens <= 0 {
+		reserveTokens = defaultReserveTokens
+	}
+	return &Budgeter{
+		contextWindow: contextWindow,
+		reserveTokens: reserveTokens,
+		summarizer:    summarizer,
+	}
+}
+
+// Fit builds a prompt from the system prompt and turns that stays within the context window
+// summary and summarizedTurns describe the rolling summary stored for the conversation
+func (b *Budgeter) Fit(ctx context.Context, systemPrompt string, turns []Turn, summary string, summarizedTurns int) (Result, error) {
+	if summarizedTurns > len(turns) {
+		summary, summarizedTurns = "", 0
+	}
+
+	budget := b.contextWindow - b.reserveTokens - EstimateTokens(systemPrompt)
+	summaryBudget := 0
+	if b.summarizer != nil {
+		// Keep room for the summary that replaces older turns
+		summaryBudget = budget / 4
+		if summaryBudget > 1024 {
+			summaryBudget = 1024
+		}
+	}
+
+	// Walk backwards keeping the most recent turns that fit; the latest turn is always kept
+	start := len(turns)
+	used := 0
+	for i := len(turns) - 1; i >= 0; i-- {
+		cost := EstimateTokens(formatTurn(turns[i])) + 1
+		if i < len(turns)-1 && used+cost > budget-summaryBudget {
+			break
+		}
+		used += cost
+		start = i
+	}
+
+	result := Result{
+		Summary:         summary,
+		SummarizedTurns: summarizedTurns,
+	}
+
+	if start == 0 {
+		// Everything fits, no summary needed
+		result.Prompt = FormatTurns(turns)
+		return result, nil
+	}
+
+	if b.summarizer != nil {
+		if start > summarizedTurns {
+			newSummary, err := b.summarizer(ctx, summary, turns[summarizedTurns:start])
+			if err != nil {
+				return Result{}, fmt.Errorf("error summarizing history: %w", err)
+			}
+			result.Summary = strings.TrimSpace(newSummary)
+			result.SummarizedTurns = start
+			result.Summarized = true
+		}
+		// The stored summary may cover fewer turns than were dropped, keep the rest verbatim
+		start = result.SummarizedTurns
+	}
+
+	result.OmittedTurns = start
+
+	var sb strings.Builder
+	if b.summarizer != nil && result.Summary != "" {
+		sb.WriteString(summaryPrefix + result.Summary + "\n")
+	}
+	sb.WriteString(FormatTurns(turns[start:]))
+	result.Prompt = sb.String()
+
+	return result, nil
+}
+
+// FormatTurns joins turns into the "User: ... / Assistant: ..." format sent to providers
+func FormatTurns(turns []Turn) string {
+	lines := make([]string, 0, len(turns))
+	for _, turn := range turns {
+		lines = append(lines, formatTurn(turn))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func formatTurn(turn Turn) string {
+	switch turn.Role {
+	case "assistant":
+		return "Assistant: " + turn.Content
+	case "search":
+		return "Search results: " + turn.Content
+	default:
+		return "User: " + turn.Content
+	}
+}
+
+// EstimateTokens approximates the token count of text at roughly four characters per token
+func EstimateTokens(text string) int {
+	if text == "" {
+		return 0
+	}
+	return (len(text) + 3) / 4
+}
@@ -0,0 +1,207 @@
+package history
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// testTurns returns n alternating user and assistant turns that each cost 13 tokens in the prompt
+func testTurns(n int) []Turn {
+	turns := make([]Turn, n)
+	for i := range turns {
+		role, prefix := "user", "User: "
+		if i%2 == 1 {
+			role, prefix = "assistant", "Assistant: "
+		}
+		content := fmt.Sprintf("turn %02d ", i)
+		turns[i] = Turn{Role: role, Content: content + strings.Repeat("x", 48-len(prefix)-len(content))}
+	}
+	return turns
+}
+
+// summarizerCall records what a Summarizer was asked to condense
+type summarizerCall struct {
+	previous string
+	from, to int // The turns passed, as indexes into the test's turns
+}
+
+func TestBudgeterFit(t *testing.T) {
+	// With a context window of 100 and 20 reserved tokens, 80 tokens are left for the history:
+	// six 13-token turns, or four next to the 20 tokens kept for a summary
+	const contextWindow, reserveTokens = 100, 20
+
+	tests := []struct {
+		name            string
+		turns           int
+		systemPrompt    string
+		summary         string
+		summarizedTurns int
+		summarizer      bool
+
+		wantCall            *summarizerCall
+		wantSummary         string
+		wantSummarizedTurns int
+		wantOmitted         int
+		wantSummarized      bool
+	}{
+		{
+			name:  "history fits",
+			turns: 3,
+		},
+		{
+			name:       "history fits with a summarizer",
+			turns:      4,
+			summarizer: true,
+		},
+		{
+			name:        "oldest turns are dropped without a summarizer",
+			turns:       10,
+			wantOmitted: 4,
+		},
+		{
+			name:                "older turns are summarized",
+			turns:               10,
+			summarizer:          true,
+			wantCall:            &summarizerCall{previous: "", from: 0, to: 6},
+			wantSummary:         "summary of 0-6",
+			wantSummarizedTurns: 6,
+			wantOmitted:         6,
+			wantSummarized:      true,
+		},
+		{
+			name:                "existing summary is extended",
+			turns:               10,
+			summary:             "summary of 0-3",
+			summarizedTurns:     3,
+			summarizer:          true,
+			wantCall:            &summarizerCall{previous: "summary of 0-3", from: 3, to: 6},
+			wantSummary:         "summary of 3-6",
+			wantSummarizedTurns: 6,
+			wantOmitted:         6,
+			wantSummarized:      true,
+		},
+		{
+			name:                "existing summary already covers the dropped turns",
+			turns:               10,
+			summary:             "summary of 0-8",
+			summarizedTurns:     8,
+			summarizer:          true,
+			wantSummary:         "summary of 0-8",
+			wantSummarizedTurns: 8,
+			wantOmitted:         8,
+		},
+		{
+			name:            "summary covering more turns than the history is reset",
+			turns:           3,
+			summary:         "summary of another branch",
+			summarizedTurns: 5,
+			summarizer:      true,
+		},
+		{
+			name:                "reset summary is rebuilt from the first turn",
+			turns:               10,
+			summary:             "summary of another branch",
+			summarizedTurns:     12,
+			summarizer:          true,
+			wantCall:            &summarizerCall{previous: "", from: 0, to: 6},
+			wantSummary:         "summary of 0-6",
+			wantSummarizedTurns: 6,
+			wantOmitted:         6,
+			wantSummarized:      true,
+		},
+		{
+			name:         "system prompt larger than the budget keeps only the latest turn",
+			turns:        5,
+			systemPrompt: strings.Repeat("s", 400),
+			wantOmitted:  4,
+		},
+		{
+			name:                "system prompt larger than the budget summarizes everything else",
+			turns:               5,
+			systemPrompt:        strings.Repeat("s", 400),
+			summarizer:          true,
+			wantCall:            &summarizerCall{previous: "", from: 0, to: 4},
+			wantSummary:         "summary of 0-4",
+			wantSummarizedTurns: 4,
+			wantOmitted:         4,
+			wantSummarized:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			turns := testTurns(tt.turns)
+
+			var calls []summarizerCall
+			var summarizer Summarizer
+			if tt.summarizer {
+				summarizer = func(ctx context.Context, previous string, summarized []Turn) (string, error) {
+					from := 0
+					fmt.Sscanf(summarized[0].Content, "turn %d", &from)
+					calls = append(calls, summarizerCall{previous: previous, from: from, to: from + len(summarized)})
+					return fmt.Sprintf(" summary of %d-%d\n", from, from+len(summarized)), nil
+				}
+			}
+
+			b := NewBudgeter("test-model", contextWindow, reserveTokens, summarizer)
+			result, err := b.Fit(context.Background(), tt.systemPrompt, turns, tt.summary, tt.summarizedTurns)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			switch {
+			case tt.wantCall == nil && len(calls) > 0:
+				t.Errorf("summarizer called with %+v, want no call", calls)
+			case tt.wantCall != nil && (len(calls) != 1 || calls[0] != *tt.wantCall):
+				t.Errorf("summarizer called with %+v, want %+v", calls, *tt.wantCall)
+			}
+
+			if result.Summary != tt.wantSummary {
+				t.Errorf("got summary %q, want %q", result.Summary, tt.wantSummary)
+			}
+			if result.SummarizedTurns != tt.wantSummarizedTurns {
+				t.Errorf("got %d summarized turns, want %d", result.SummarizedTurns, tt.wantSummarizedTurns)
+			}
+			if result.OmittedTurns != tt.wantOmitted {
+				t.Errorf("got %d omitted turns, want %d", result.OmittedTurns, tt.wantOmitted)
+			}
+			if result.Summarized != tt.wantSummarized {
+				t.Errorf("got summarized %v, want %v", result.Summarized, tt.wantSummarized)
+			}
+			if result.Truncated() != (tt.wantOmitted > 0) {
+				t.Errorf("got truncated %v with %d omitted turns", result.Truncated(), tt.wantOmitted)
+			}
+
+			wantPrompt := FormatTurns(turns[tt.wantOmitted:])
+			if tt.wantOmitted > 0 && tt.wantSummary != "" {
+				wantPrompt = summaryPrefix + tt.wantSummary + "\n" + wantPrompt
+			}
+			if result.Prompt != wantPrompt {
+				t.Errorf("got prompt\n%s\nwant\n%s", result.Prompt, wantPrompt)
+			}
+		})
+	}
+}
+
+func TestBudgeterFitSummarizerError(t *testing.T) {
+	summarizer := func(ctx context.Context, previous string, turns []Turn) (string, error) {
+		return "", errors.New("provider unavailable")
+	}
+	b := NewBudgeter("test-model", 100, 20, summarizer)
+	if _, err := b.Fit(context.Background(), "", testTurns(10), "", 0); err == nil {
+		t.Fatal("got no error from a failing summarizer")
+	}
+}
+
+func TestNewBudgeterDefaults(t *testing.T) {
+	b := NewBudgeter("unknown-model", 0, 0, nil)
+	if b.contextWindow != ContextWindow("unknown-model") {
+		t.Errorf("got context window %d, want %d", b.contextWindow, ContextWindow("unknown-model"))
+	}
+	if b.reserveTokens != defaultReserveTokens {
+		t.Errorf("got %d reserved tokens, want %d", b.reserveTokens, defaultReserveTokens)
+	}
+}
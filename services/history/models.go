@@ -0,0 +1,47 @@
+package history
+
+import (
+	"strings"
+
+	"github.com/tedfulk/goatmeal/utils/models"
+)
+
+// contextWindows maps model name prefixes to their context window in tokens
+// More specific prefixes must come before shorter ones
+var contextWindows = []struct {
+	prefix string
+	tokens int
+}{
+	{"gpt-4o", 128000},
+	{"gpt-4-turbo", 128000},
+	{"gpt-4.1", 1000000},
+	{"gpt-4-32k", 32768},
+	{"gpt-4", 8192},
+	{"gpt-3.5-turbo", 16385},
+	{"o1", 128000},
+	{"o3", 200000},
+	{"claude", 200000},
+	{"gemini-1.5-pro", 2000000},
+	{"gemini", 1000000},
+	{"deepseek", 64000},
+	{"llama-3.1", 128000},
+	{"llama-3.2", 128000},
+	{"llama-3.3", 128000},
+	{"llama3", 8192},
+	{"mixtral", 32768},
+	{"gemma", 8192},
+	{"qwen", 32768},
+	{"mistral", 32768},
+}
+
+// ContextWindow returns the context window in tokens for a model
+// Unknown models get a conservative default
+func ContextWindow(model string) int {
+	name := strings.ToLower(models.StripModelsPrefix(model))
+	for _, cw := range contextWindows {
+		if strings.HasPrefix(name, cw.prefix) {
+			return cw.tokens
+		}
+	}
+	return defaultContextWindow
+}
@@ -0,0 +1,20 @@
+package history
+
+import (
+	"context"
+
+	"github.com/tedfulk/goatmeal/utils/prompts"
+)
+
+// MessageSender is the part of providers.Provider needed to generate summaries
+type MessageSender interface {
+	SendMessage(ctx context.Context, message, systemPrompt, model string) (string, error)
+}
+
+// NewSummarizer returns a Summarizer that uses the given provider and model
+func NewSummarizer(sender MessageSender, model string) Summarizer {
+	return func(ctx context.Context, previousSummary string, turns []Turn) (string, error) {
+		prompt := prompts.GetSummarizeHistoryPrompt(previousSummary, FormatTurns(turns))
+		return sender.SendMessage(ctx, prompt, prompts.GetSummarizeHistorySystemPrompt(), model)
+	}
+}
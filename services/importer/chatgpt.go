@@ -0,0 +1,169 @@
+package importer
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tedfulk/goatmeal/database"
+)
+
+// chatGPTConversation is a conversation in ChatGPT's conversations.json
+// Messages form a tree in mapping, with current_node the last message of the branch shown
+type chatGPTConversation struct {
+	ID             string                 `json:"id"`
+	ConversationID string                 `json:"conversation_id"`
+	Title          string                 `json:"title"`
+	CreateTime     float64                `json:"create_time"`
+	UpdateTime     float64                `json:"update_time"`
+	CurrentNode    string                 `json:"current_node"`
+	DefaultModel   string                 `json:"default_model_slug"`
+	Mapping        map[string]chatGPTNode `json:"mapping"`
+}
+
+type chatGPTNode struct {
+	ID       string          `json:"id"`
+	Parent   string          `json:"parent"`
+	Children []string        `json:"children"`
+	Message  *chatGPTMessage `json:"message"`
+}
+
+type chatGPTMessage struct {
+	Author struct {
+		Role string `json:"role"`
+	} `json:"author"`
+	CreateTime float64 `json:"create_time"`
+	Content    struct {
+		ContentType string            `json:"content_type"`
+		Parts       []json.RawMessage `json:"parts"`
+		Text        string            `json:"text"`
+	} `json:"content"`
+	Metadata struct {
+		ModelSlug string `json:"model_slug"`
+		Hidden    bool   `json:"is_visually_hidden_from_conversation"`
+	} `json:"metadata"`
+}
+
+// text returns the message as markdown, or "" for messages that aren't shown in the chat,
+// such as system and tool messages, custom instructions and images
+func (m *chatGPTMessage) text() string {
+	if m == nil || m.Metadata.Hidden || (m.Author.Role != "user" && m.Author.Role != "assistant") {
+		return ""
+	}
+
+	switch m.Content.ContentType {
+	case "text", "multimodal_text":
+		var parts []string
+		for _, raw := range m.Content.Parts {
+			var part string
+			if json.Unmarshal(raw, &part) == nil && strings.TrimSpace(part) != "" {
+				parts = append(parts, part)
+			}
+		}
+		return strings.Join(parts, "\n\n")
+	case "code":
+		if strings.TrimSpace(m.Content.Text) == "" {
+			return ""
+		}
+		return "```\n" + m.Content.Text + "\n```"
+	}
+	return ""
+}
+
+// convert returns the conversation with the messages shown in ChatGPT, keeping its branches
+func (c chatGPTConversation) convert() database.Conversation {
+	id := c.ConversationID
+	if id == "" {
+		id = c.ID
+	}
+	conv := database.Conversation{
+		ID:        importID("chatgpt", id),
+		Title:     strings.TrimSpace(c.Title),
+		Provider:  "openai",
+		Model:     c.DefaultModel,
+		CreatedAt: unixTime(c.CreateTime),
+		UpdatedAt: unixTime(c.UpdateTime),
+		Tags:      []string{"chatgpt"},
+	}
+	if conv.Title == "" {
+		conv.Title = untitled
+	}
+
+	// keptAncestor returns the nearest node from nodeID up that is imported, skipping hidden messages
+	// The walk is bounded in case of a cycle in damaged data
+	keptAncestor := func(nodeID string) string {
+		for range c.Mapping {
+			node, ok := c.Mapping[nodeID]
+			if !ok {
+				return ""
+			}
+			if node.Message.text() != "" {
+				return nodeID
+			}
+			nodeID = node.Parent
+		}
+		return ""
+	}
+
+	// Walk the tree from its roots so parents come before their replies
+	var queue []string
+	for nodeID, node := range c.Mapping {
+		if _, ok := c.Mapping[node.Parent]; !ok {
+			queue = append(queue, nodeID)
+		}
+	}
+	sort.Strings(queue)
+	seen := make(map[string]bool)
+	last := conv.CreatedAt
+	for len(queue) > 0 {
+		nodeID := queue[0]
+		queue = queue[1:]
+		if seen[nodeID] {
+			continue
+		}
+		seen[nodeID] = true
+		node := c.Mapping[nodeID]
+		queue = append(queue, node.Children...)
+
+		text := node.Message.text()
+		if text == "" {
+			continue
+		}
+		msg := database.Message{
+			ID:        importID("chatgpt", id, nodeID),
+			Role:      node.Message.Author.Role,
+			Content:   text,
+			CreatedAt: unixTime(node.Message.CreateTime),
+		}
+		if msg.CreatedAt.IsZero() {
+			msg.CreatedAt = last
+		}
+		last = msg.CreatedAt
+		if parent := keptAncestor(node.Parent); parent != "" {
+			msg.ParentID = importID("chatgpt", id, parent)
+		}
+		if msg.Role == "assistant" {
+			msg.Provider = "openai"
+			msg.Model = node.Message.Metadata.ModelSlug
+		}
+		conv.Messages = append(conv.Messages, msg)
+	}
+
+	if leaf := keptAncestor(c.CurrentNode); leaf != "" {
+		conv.ActiveLeafID = importID("chatgpt", id, leaf)
+	}
+	conversationTimes(&conv)
+	conversationModel(&conv)
+	return conv
+}
+
+// unixTime converts a ChatGPT timestamp in fractional seconds, returning the zero time for 0
+func unixTime(seconds float64) time.Time {
+	if seconds <= 0 {
+		return time.Time{}
+	}
+	whole, frac := math.Modf(seconds)
+	return time.Unix(int64(whole), int64(frac*1e9))
+}
@@ -0,0 +1,102 @@
+package importer
+
+import (
+	"strings"
+	"time"
+
+	"github.com/tedfulk/goatmeal/database"
+)
+
+// claudeRoot is the parent_message_uuid of the first message in Claude.ai exports
+const claudeRoot = "00000000-0000-4000-8000-000000000000"
+
+// claudeConversation is a conversation in the Claude.ai conversations.json
+type claudeConversation struct {
+	UUID         string          `json:"uuid"`
+	Name         string          `json:"name"`
+	Model        string          `json:"model"`
+	CreatedAt    time.Time       `json:"created_at"`
+	UpdatedAt    time.Time       `json:"updated_at"`
+	ChatMessages []claudeMessage `json:"chat_messages"`
+}
+
+type claudeMessage struct {
+	UUID       string    `json:"uuid"`
+	Sender     string    `json:"sender"` // human or assistant
+	Text       string    `json:"text"`
+	CreatedAt  time.Time `json:"created_at"`
+	ParentUUID string    `json:"parent_message_uuid"` // Missing in older exports, where messages follow each other
+	Content    []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+}
+
+// text returns the text blocks of the message, falling back to its plain text
+func (m claudeMessage) text() string {
+	var blocks []string
+	for _, block := range m.Content {
+		if block.Type == "text" && strings.TrimSpace(block.Text) != "" {
+			blocks = append(blocks, block.Text)
+		}
+	}
+	if len(blocks) > 0 {
+		return strings.Join(blocks, "\n\n")
+	}
+	return strings.TrimSpace(m.Text)
+}
+
+// convert returns the conversation with its branches when the export records them
+func (c claudeConversation) convert() database.Conversation {
+	conv := database.Conversation{
+		ID:        importID("claude", c.UUID),
+		Title:     strings.TrimSpace(c.Name),
+		Provider:  "anthropic",
+		Model:     c.Model,
+		CreatedAt: c.CreatedAt,
+		UpdatedAt: c.UpdatedAt,
+		Tags:      []string{"claude"},
+	}
+	if conv.Title == "" {
+		conv.Title = untitled
+	}
+
+	known := make(map[string]bool)
+	var previous string
+	for _, m := range c.ChatMessages {
+		text := m.text()
+		if text == "" {
+			continue
+		}
+		msg := database.Message{
+			ID:        importID("claude", c.UUID, m.UUID),
+			Role:      "user",
+			Content:   text,
+			CreatedAt: m.CreatedAt,
+		}
+		if m.Sender == "assistant" {
+			msg.Role = "assistant"
+			msg.Provider = "anthropic"
+			msg.Model = c.Model
+		}
+
+		switch {
+		case m.ParentUUID == "":
+			msg.ParentID = previous
+		case m.ParentUUID != claudeRoot && known[m.ParentUUID]:
+			msg.ParentID = importID("claude", c.UUID, m.ParentUUID)
+		case m.ParentUUID != claudeRoot:
+			// The parent was left out, e.g. because it had no text
+			msg.ParentID = previous
+		}
+		known[m.UUID] = true
+		previous = msg.ID
+		conv.Messages = append(conv.Messages, msg)
+	}
+
+	conversationTimes(&conv)
+	if conv.Model == "" {
+		conv.Model = "claude"
+	}
+	return conv
+}
@@ -0,0 +1,61 @@
+package importer
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tedfulk/goatmeal/database"
+)
+
+// convertGoatmeal returns a conversation from a goatmeal JSON export or archive with its original IDs
+// Exports made before messages had IDs get stable ones, with each message following the one before it
+func convertGoatmeal(c database.ExportedConversation) database.Conversation {
+	conv := database.Conversation{
+		ID:           c.ID,
+		Title:        strings.TrimSpace(c.Title),
+		Provider:     c.Provider,
+		Model:        c.Model,
+		CreatedAt:    c.CreatedAt,
+		UpdatedAt:    c.UpdatedAt,
+		Pinned:       c.Pinned,
+		Tags:         c.Tags,
+		ActiveLeafID: c.ActiveLeafID,
+	}
+	if conv.ID == "" {
+		conv.ID = importID("goatmeal", c.Title, c.CreatedAt.Format(time.RFC3339Nano))
+	}
+	if conv.Title == "" {
+		conv.Title = untitled
+	}
+
+	var previous string
+	for i, m := range c.Messages {
+		msg := database.Message{
+			ID:        m.ID,
+			Role:      m.Role,
+			Content:   m.Content,
+			CreatedAt: m.Timestamp,
+			ParentID:  m.ParentID,
+			Provenance: database.Provenance{
+				Provider:         m.Provider,
+				Model:            m.Model,
+				SystemPrompt:     m.SystemPrompt,
+				SystemPromptHash: m.SystemPromptHash,
+				Params:           string(m.Params),
+				Latency:          time.Duration(m.LatencyMS) * time.Millisecond,
+				InputTokens:      m.InputTokens,
+				OutputTokens:     m.OutputTokens,
+			},
+		}
+		if msg.ID == "" {
+			msg.ID = importID("goatmeal", conv.ID, strconv.Itoa(i))
+			msg.ParentID = previous
+		}
+		previous = msg.ID
+		conv.Messages = append(conv.Messages, msg)
+	}
+
+	conversationTimes(&conv)
+	return conv
+}
@@ -0,0 +1,248 @@
+// Package importer reads conversations exported from ChatGPT, Claude.ai and goatmeal
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tedfulk/goatmeal/database"
+)
+
+// Names of the supported export formats
+const (
+	FormatChatGPT  = "ChatGPT"
+	FormatClaude   = "Claude.ai"
+	FormatGoatmeal = "goatmeal"
+)
+
+// untitled is the title of imported conversations without one
+const untitled = "Imported conversation"
+
+// namespace makes the IDs of imported conversations stable, so importing a file twice skips them
+var namespace = uuid.NewSHA1(uuid.NameSpaceURL, []byte("https://github.com/tedfulk/goatmeal/import"))
+
+// Result describes an import
+type Result struct {
+	Format   string // Export format of the file, e.g. ChatGPT
+	Imported int
+	Skipped  int // Conversations that were imported before
+}
+
+// String summarizes the import, e.g. "Imported 12 ChatGPT conversations, skipped 3 imported before"
+func (r Result) String() string {
+	summary := fmt.Sprintf("Imported %d %s conversations", r.Imported, r.Format)
+	if r.Skipped > 0 {
+		summary += fmt.Sprintf(", skipped %d imported before", r.Skipped)
+	}
+	return summary
+}
+
+// ImportFile imports every conversation in the export at path
+// path can be a JSON or JSONL file, a gzip compressed one, or a zip archive containing conversations.json
+func ImportFile(db *database.DB, path string) (Result, error) {
+	data, err := readExport(path)
+	if err != nil {
+		return Result{}, err
+	}
+	format, conversations, err := Parse(data)
+	if err != nil {
+		return Result{}, err
+	}
+
+	result := Result{Format: format}
+	for i := range conversations {
+		imported, err := db.ImportConversation(&conversations[i])
+		if err != nil {
+			return result, fmt.Errorf("error importing %q: %w", conversations[i].Title, err)
+		}
+		if imported {
+			result.Imported++
+		} else {
+			result.Skipped++
+		}
+	}
+	return result, nil
+}
+
+// Parse reads the conversations of an export and returns the name of its format
+// Conversations without any user or assistant messages are left out
+func Parse(data []byte) (string, []database.Conversation, error) {
+	var format string
+	var conversations []database.Conversation
+
+	// A file holds one JSON value, or one per line for JSONL archives
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	for {
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err == io.EOF {
+			break
+		} else if err != nil {
+			return "", nil, fmt.Errorf("error reading export: %w", err)
+		}
+
+		values := []json.RawMessage{value}
+		if bytes.HasPrefix(bytes.TrimSpace(value), []byte("[")) {
+			values = nil
+			if err := json.Unmarshal(value, &values); err != nil {
+				return "", nil, fmt.Errorf("error reading export: %w", err)
+			}
+		}
+
+		for _, raw := range values {
+			conv, valueFormat, err := parseConversation(raw)
+			if err != nil {
+				return "", nil, err
+			}
+			if format == "" {
+				format = valueFormat
+			}
+			if len(conv.Messages) > 0 {
+				conversations = append(conversations, conv)
+			}
+		}
+	}
+
+	if format == "" {
+		return "", nil, errors.New("the file contains no conversations")
+	}
+	return format, conversations, nil
+}
+
+// parseConversation converts one exported conversation, recognizing its format by its fields
+func parseConversation(raw json.RawMessage) (database.Conversation, string, error) {
+	var probe struct {
+		Mapping      json.RawMessage `json:"mapping"`
+		ChatMessages json.RawMessage `json:"chat_messages"`
+		Messages     json.RawMessage `json:"messages"`
+	}
+	if err := json.Unmarshal(raw, &probe); err != nil {
+		return database.Conversation{}, "", fmt.Errorf("unrecognized export: %w", err)
+	}
+
+	switch {
+	case probe.Mapping != nil:
+		var conv chatGPTConversation
+		if err := json.Unmarshal(raw, &conv); err != nil {
+			return database.Conversation{}, "", fmt.Errorf("error reading ChatGPT conversation: %w", err)
+		}
+		return conv.convert(), FormatChatGPT, nil
+	case probe.ChatMessages != nil:
+		var conv claudeConversation
+		if err := json.Unmarshal(raw, &conv); err != nil {
+			return database.Conversation{}, "", fmt.Errorf("error reading Claude.ai conversation: %w", err)
+		}
+		return conv.convert(), FormatClaude, nil
+	case probe.Messages != nil:
+		var conv database.ExportedConversation
+		if err := json.Unmarshal(raw, &conv); err != nil {
+			return database.Conversation{}, "", fmt.Errorf("error reading goatmeal conversation: %w", err)
+		}
+		return convertGoatmeal(conv), FormatGoatmeal, nil
+	default:
+		return database.Conversation{}, "", errors.New("unrecognized export: expected a ChatGPT, Claude.ai or goatmeal conversation")
+	}
+}
+
+// readExport returns the contents of an export file, unpacking zip and gzip files
+func readExport(filename string) ([]byte, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", filename, err)
+	}
+
+	switch {
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")):
+		// ChatGPT and Claude.ai exports are zip archives with the conversations in conversations.json
+		archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, fmt.Errorf("error opening %s: %w", filename, err)
+		}
+		for _, file := range archive.File {
+			if path.Base(file.Name) != "conversations.json" {
+				continue
+			}
+			r, err := file.Open()
+			if err != nil {
+				return nil, fmt.Errorf("error opening %s: %w", file.Name, err)
+			}
+			defer r.Close()
+			return io.ReadAll(r)
+		}
+		return nil, fmt.Errorf("%s has no conversations.json", filename)
+	case bytes.HasPrefix(data, []byte{0x1f, 0x8b}):
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("error opening %s: %w", filename, err)
+		}
+		defer r.Close()
+		return io.ReadAll(r)
+	}
+	return data, nil
+}
+
+// importID returns the ID of an imported conversation or message
+// The same source and ID always give the same goatmeal ID
+func importID(source string, ids ...string) string {
+	name := source
+	for _, id := range ids {
+		name += ":" + id
+	}
+	return uuid.NewSHA1(namespace, []byte(name)).String()
+}
+
+// conversationTimes fills in missing conversation timestamps from its messages
+// Times are converted to local time, the same as those of conversations started in goatmeal
+func conversationTimes(conv *database.Conversation) {
+	if len(conv.Messages) == 0 {
+		return
+	}
+	for i := range conv.Messages {
+		conv.Messages[i].CreatedAt = conv.Messages[i].CreatedAt.Local()
+	}
+	conv.CreatedAt = conv.CreatedAt.Local()
+	conv.UpdatedAt = conv.UpdatedAt.Local()
+	if conv.CreatedAt.IsZero() {
+		conv.CreatedAt = conv.Messages[0].CreatedAt
+	}
+	for _, msg := range conv.Messages {
+		if msg.CreatedAt.After(conv.UpdatedAt) {
+			conv.UpdatedAt = msg.CreatedAt
+		}
+	}
+	if conv.CreatedAt.IsZero() {
+		conv.CreatedAt = time.Now()
+	}
+	if conv.UpdatedAt.IsZero() {
+		conv.UpdatedAt = conv.CreatedAt
+	}
+}
+
+// conversationModel sets the conversation's model to the one of the latest reply in its active branch
+func conversationModel(conv *database.Conversation) {
+	branch := database.ActiveBranch(sortedMessages(conv.Messages), conv.ActiveLeafID)
+	for i := len(branch) - 1; i >= 0; i-- {
+		if branch[i].Role == "assistant" && branch[i].Model != "" {
+			conv.Model = branch[i].Model
+			return
+		}
+	}
+}
+
+// sortedMessages returns a copy of messages ordered by creation time, as ActiveBranch expects
+func sortedMessages(messages []database.Message) []database.Message {
+	sorted := append([]database.Message(nil), messages...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].CreatedAt.Before(sorted[j].CreatedAt)
+	})
+	return sorted
+}
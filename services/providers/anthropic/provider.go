@@ -31,6 +31,12 @@ func NewProvider(apiKey string) *Provider {
 
 // SendMessage sends a message to Anthropic and returns the response
 func (p *Provider) SendMessage(ctx context.Context, message, systemPrompt, model string) (string, error) {
+	resp, err := p.SendMessageDetailed(ctx, message, systemPrompt, model)
+	return resp.Content, err
+}
+
+// SendMessageDetailed sends a message to Anthropic and returns the response with its token usage
+func (p *Provider) SendMessageDetailed(ctx context.Context, message, systemPrompt, model string) (providers.Response, error) {
 	url := fmt.Sprintf("%s/messages", baseURL)
 	
 	// Combine system prompt and user message
@@ -44,14 +50,16 @@ func (p *Provider) SendMessage(ctx context.Context, message, systemPrompt, model
 		"messages":   messages,
 	}
 
+	response := providers.Response{Params: providers.GenerationParams(payload)}
+
 	jsonPayload, err := json.Marshal(payload)
 	if err != nil {
-		return "", fmt.Errorf("error marshaling request: %w", err)
+		return response, fmt.Errorf("error marshaling request: %w", err)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, "POST", url, strings.NewReader(string(jsonPayload)))
 	if err != nil {
-		return "", fmt.Errorf("error creating request: %w", err)
+		return response, fmt.Errorf("error creating request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -60,29 +68,36 @@ func (p *Provider) SendMessage(ctx context.Context, message, systemPrompt, model
 
 	resp, err := p.client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("error sending request: %w", err)
+		return response, fmt.Errorf("error sending request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return response, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
 
 	var result struct {
 		Content []struct {
 			Text string `json:"text"`
 		} `json:"content"`
+		Usage struct {
+			InputTokens  int `json:"input_tokens"`
+			OutputTokens int `json:"output_tokens"`
+		} `json:"usage"`
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", fmt.Errorf("error decoding response: %w", err)
+		return response, fmt.Errorf("error decoding response: %w", err)
 	}
 
 	if len(result.Content) == 0 {
-		return "", fmt.Errorf("no response from anthropic")
+		return response, fmt.Errorf("no response from anthropic")
 	}
 
-	return result.Content[0].Text, nil
+	response.Content = result.Content[0].Text
+	response.InputTokens = result.Usage.InputTokens
+	response.OutputTokens = result.Usage.OutputTokens
+	return response, nil
 }
 
 // ListModels returns a list of available Anthropic models
@@ -27,10 +27,20 @@ func NewProvider(apiKey string) *Provider {
 
 // SendMessage sends a message to Gemini and returns the response
 func (p *Provider) SendMessage(ctx context.Context, message, systemPrompt, model string) (string, error) {
+	resp, err := p.SendMessageDetailed(ctx, message, systemPrompt, model)
+	return resp.Content, err
+}
+
+// SendMessageDetailed sends a message to Gemini and returns the response with its token usage
+func (p *Provider) SendMessageDetailed(ctx context.Context, message, systemPrompt, model string) (providers.Response, error) {
+	response := providers.Response{
+		Params: map[string]interface{}{"temperature": 0.2, "top_k": 40, "top_p": 0.95},
+	}
+
 	if p.client == nil {
 		client, err := genai.NewClient(ctx, option.WithAPIKey(p.GetAPIKey()))
 		if err != nil {
-			return "", fmt.Errorf("error creating Gemini client: %w", err)
+			return response, fmt.Errorf("error creating Gemini client: %w", err)
 		}
 		p.client = client
 	}
@@ -72,20 +82,25 @@ func (p *Provider) SendMessage(ctx context.Context, message, systemPrompt, model
 	// Send the message
 	resp, err := cs.SendMessage(ctx, genai.Text(message))
 	if err != nil {
-		return "", fmt.Errorf("error sending message: %w", err)
+		return response, fmt.Errorf("error sending message: %w", err)
 	}
 
 	if len(resp.Candidates) == 0 {
-		return "", fmt.Errorf("no response from Gemini")
+		return response, fmt.Errorf("no response from Gemini")
 	}
 
 	// Get the response text
 	text, ok := resp.Candidates[0].Content.Parts[0].(genai.Text)
 	if !ok {
-		return "", fmt.Errorf("unexpected response type from Gemini")
+		return response, fmt.Errorf("unexpected response type from Gemini")
 	}
 
-	return string(text), nil
+	response.Content = string(text)
+	if resp.UsageMetadata != nil {
+		response.InputTokens = int(resp.UsageMetadata.PromptTokenCount)
+		response.OutputTokens = int(resp.UsageMetadata.CandidatesTokenCount)
+	}
+	return response, nil
 }
 
 // SendMessageWithImage sends a message with an image to Gemini and returns the response
@@ -0,0 +1,232 @@
+package ollama
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// PullProgress represents a single status update streamed from Ollama's /pull endpoint
+type PullProgress struct {
+	Status    string `json:"status"`
+	Digest    string `json:"digest,omitempty"`
+	Total     int64  `json:"total,omitempty"`
+	Completed int64  `json:"completed,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Percent returns the download progress of the current layer between 0 and 1
+func (p PullProgress) Percent() float64 {
+	if p.Total <= 0 {
+		return 0
+	}
+	return float64(p.Completed) / float64(p.Total)
+}
+
+// ModelDetails represents the response structure from Ollama's /show endpoint
+type ModelDetails struct {
+	Modelfile  string `json:"modelfile"`
+	Parameters string `json:"parameters"`
+	Template   string `json:"template"`
+	License    string `json:"license"`
+	Details    struct {
+		Format            string   `json:"format"`
+		Family            string   `json:"family"`
+		Families          []string `json:"families"`
+		ParameterSize     string   `json:"parameter_size"`
+		QuantizationLevel string   `json:"quantization_level"`
+	} `json:"details"`
+	ModifiedAt time.Time `json:"modified_at"`
+}
+
+// RunningModel represents a model currently loaded into memory, from Ollama's /ps endpoint
+type RunningModel struct {
+	Name      string    `json:"name"`
+	Model     string    `json:"model"`
+	Size      int64     `json:"size"`
+	SizeVRAM  int64     `json:"size_vram"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Details   struct {
+		ParameterSize     string `json:"parameter_size"`
+		QuantizationLevel string `json:"quantization_level"`
+	} `json:"details"`
+}
+
+// PullModel downloads a model from the Ollama library
+// progress is called for every status update streamed by the server
+func (p *Provider) PullModel(ctx context.Context, model string, progress func(PullProgress)) error {
+	url := fmt.Sprintf("%s/pull", defaultBaseURL)
+
+	payload := map[string]interface{}{
+		"model":  model,
+		"stream": true,
+	}
+
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, strings.NewReader(string(jsonPayload)))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d: %s", resp.StatusCode, readError(resp.Body))
+	}
+
+	// The response is a stream of newline-delimited JSON objects
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var update PullProgress
+		if err := json.Unmarshal(line, &update); err != nil {
+			return fmt.Errorf("decode progress: %w", err)
+		}
+		if update.Error != "" {
+			return fmt.Errorf("pull %s: %s", model, update.Error)
+		}
+		if progress != nil {
+			progress(update)
+		}
+		if update.Status == "success" {
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read progress: %w", err)
+	}
+
+	return fmt.Errorf("pull %s: stream ended before completion", model)
+}
+
+// DeleteModel removes a model and its data from the Ollama server
+func (p *Provider) DeleteModel(ctx context.Context, model string) error {
+	url := fmt.Sprintf("%s/delete", defaultBaseURL)
+
+	jsonPayload, err := json.Marshal(map[string]string{"model": model})
+	if err != nil {
+		return fmt.Errorf("marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "DELETE", url, strings.NewReader(string(jsonPayload)))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d: %s", resp.StatusCode, readError(resp.Body))
+	}
+
+	return nil
+}
+
+// ShowModel returns the parameters, template and quantization details of a model
+func (p *Provider) ShowModel(ctx context.Context, model string) (*ModelDetails, error) {
+	url := fmt.Sprintf("%s/show", defaultBaseURL)
+
+	jsonPayload, err := json.Marshal(map[string]string{"model": model})
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, strings.NewReader(string(jsonPayload)))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d: %s", resp.StatusCode, readError(resp.Body))
+	}
+
+	var result ModelDetails
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+
+	return &result, nil
+}
+
+// ListRunningModels returns the models currently loaded into memory
+func (p *Provider) ListRunningModels(ctx context.Context) ([]RunningModel, error) {
+	url := fmt.Sprintf("%s/ps", defaultBaseURL)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d: %s", resp.StatusCode, readError(resp.Body))
+	}
+
+	var result struct {
+		Models []RunningModel `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+
+	return result.Models, nil
+}
+
+// readError extracts the error message from an Ollama error response body
+func readError(body io.Reader) string {
+	data, err := io.ReadAll(io.LimitReader(body, 4096))
+	if err != nil {
+		return ""
+	}
+
+	var result struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(data, &result); err == nil && result.Error != "" {
+		return result.Error
+	}
+	return strings.TrimSpace(string(data))
+}
@@ -48,6 +48,8 @@ type ollamaChatResponse struct {
 	Message struct {
 		Content string `json:"content"`
 	} `json:"message"`
+	PromptEvalCount int `json:"prompt_eval_count"`
+	EvalCount       int `json:"eval_count"`
 }
 
 // ListModels returns a list of available Ollama models
@@ -85,6 +87,12 @@ func (p *Provider) ListModels(ctx context.Context) ([]string, error) {
 
 // SendMessage sends a chat message to Ollama and returns the response
 func (p *Provider) SendMessage(ctx context.Context, message, systemPrompt, model string) (string, error) {
+	resp, err := p.SendMessageDetailed(ctx, message, systemPrompt, model)
+	return resp.Content, err
+}
+
+// SendMessageDetailed sends a chat message to Ollama and returns the response with its token usage
+func (p *Provider) SendMessageDetailed(ctx context.Context, message, systemPrompt, model string) (providers.Response, error) {
 	url := fmt.Sprintf("%s/chat", defaultBaseURL)
 
 	payload := map[string]interface{}{
@@ -102,14 +110,16 @@ func (p *Provider) SendMessage(ctx context.Context, message, systemPrompt, model
 		"stream": false,
 	}
 
+	response := providers.Response{Params: providers.GenerationParams(payload)}
+
 	jsonPayload, err := json.Marshal(payload)
 	if err != nil {
-		return "", fmt.Errorf("marshal request: %w", err)
+		return response, fmt.Errorf("marshal request: %w", err)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, "POST", url, strings.NewReader(string(jsonPayload)))
 	if err != nil {
-		return "", fmt.Errorf("create request: %w", err)
+		return response, fmt.Errorf("create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -117,18 +127,21 @@ func (p *Provider) SendMessage(ctx context.Context, message, systemPrompt, model
 	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("send request: %w", err)
+		return response, fmt.Errorf("send request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return response, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
 
 	var result ollamaChatResponse
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", fmt.Errorf("decode response: %w", err)
+		return response, fmt.Errorf("decode response: %w", err)
 	}
 
-	return result.Message.Content, nil
+	response.Content = result.Message.Content
+	response.InputTokens = result.PromptEvalCount
+	response.OutputTokens = result.EvalCount
+	return response, nil
 }
@@ -62,6 +62,12 @@ func NewOpenAICompatibleProvider(cfg OpenAICompatibleConfig) OpenAICompatiblePro
 
 // SendMessage sends a message to the provider and returns the response
 func (p OpenAICompatibleProvider) SendMessage(ctx context.Context, message, systemPrompt, model string) (string, error) {
+	resp, err := p.SendMessageDetailed(ctx, message, systemPrompt, model)
+	return resp.Content, err
+}
+
+// SendMessageDetailed sends a message to the provider and returns the response with its token usage
+func (p OpenAICompatibleProvider) SendMessageDetailed(ctx context.Context, message, systemPrompt, model string) (Response, error) {
 	url := fmt.Sprintf("%s/chat/completions", p.baseURL)
 	
 	payload := map[string]interface{}{
@@ -84,14 +90,16 @@ func (p OpenAICompatibleProvider) SendMessage(ctx context.Context, message, syst
 		payload[k] = v
 	}
 
+	response := Response{Params: GenerationParams(payload)}
+
 	jsonPayload, err := json.Marshal(payload)
 	if err != nil {
-		return "", fmt.Errorf("error marshaling request: %w", err)
+		return response, fmt.Errorf("error marshaling request: %w", err)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, "POST", url, strings.NewReader(string(jsonPayload)))
 	if err != nil {
-		return "", fmt.Errorf("error creating request: %w", err)
+		return response, fmt.Errorf("error creating request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -104,12 +112,12 @@ func (p OpenAICompatibleProvider) SendMessage(ctx context.Context, message, syst
 
 	resp, err := p.client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("error sending request: %w", err)
+		return response, fmt.Errorf("error sending request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return response, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
 
 	var result struct {
@@ -118,17 +126,24 @@ func (p OpenAICompatibleProvider) SendMessage(ctx context.Context, message, syst
 				Content string `json:"content"`
 			} `json:"message"`
 		} `json:"choices"`
+		Usage struct {
+			PromptTokens     int `json:"prompt_tokens"`
+			CompletionTokens int `json:"completion_tokens"`
+		} `json:"usage"`
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", fmt.Errorf("error decoding response: %w", err)
+		return response, fmt.Errorf("error decoding response: %w", err)
 	}
 
 	if len(result.Choices) == 0 {
-		return "", fmt.Errorf("no response from %s", p.GetName())
+		return response, fmt.Errorf("no response from %s", p.GetName())
 	}
 
-	return result.Choices[0].Message.Content, nil
+	response.Content = result.Choices[0].Message.Content
+	response.InputTokens = result.Usage.PromptTokens
+	response.OutputTokens = result.Usage.CompletionTokens
+	return response, nil
 }
 
 // ListModels returns a list of available models
@@ -17,6 +17,30 @@ type Provider interface {
 	ValidateAPIKey(ctx context.Context) error
 }
 
+// Response is a provider reply together with the metadata reported for it
+type Response struct {
+	Content      string
+	InputTokens  int                    // Prompt tokens, 0 when the provider doesn't report them
+	OutputTokens int                    // Completion tokens, 0 when the provider doesn't report them
+	Params       map[string]interface{} // Generation parameters sent with the request
+}
+
+// DetailedProvider is implemented by providers that report token usage and generation parameters
+type DetailedProvider interface {
+	SendMessageDetailed(ctx context.Context, message, systemPrompt, model string) (Response, error)
+}
+
+// GenerationParams returns a request payload without the model and messages
+func GenerationParams(payload map[string]interface{}) map[string]interface{} {
+	params := make(map[string]interface{}, len(payload))
+	for k, v := range payload {
+		if k != "model" && k != "messages" {
+			params[k] = v
+		}
+	}
+	return params
+}
+
 // BaseProvider implements common functionality for all providers
 type BaseProvider struct {
 	name    string
@@ -0,0 +1,160 @@
+package search
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Backend is a web search engine that can answer /web queries
+type Backend interface {
+	// Name returns the name used to select the backend, e.g. "tavily" or "searx"
+	Name() string
+
+	// Search runs a query with the given options
+	Search(query string, opts Options) (*SearchResponse, error)
+}
+
+// DefaultBackend is used when no backend is configured
+const DefaultBackend = "tavily"
+
+// BackendOptions holds the credentials and endpoints backends need
+type BackendOptions struct {
+	APIKeys  map[string]string
+	SearxURL string
+}
+
+// Backends lists the names of the available search backends
+var Backends = []string{"tavily", "searx", "brave", "ddg"}
+
+// CanonicalName returns the name in Backends for a backend name or alias, e.g. "searx" for
+// "SearxNG". Other names are returned in lower case
+func CanonicalName(name string) string {
+	name = strings.ToLower(name)
+	switch name {
+	case "searxng":
+		return "searx"
+	case "duckduckgo":
+		return "ddg"
+	}
+	return name
+}
+
+// NewBackend creates the search backend with the given name
+func NewBackend(name string, opts BackendOptions) (Backend, error) {
+	switch CanonicalName(name) {
+	case "", "tavily":
+		if opts.APIKeys["tavily"] == "" {
+			return nil, fmt.Errorf("please provide an API key for tavily in the settings")
+		}
+		return NewClient(opts.APIKeys["tavily"]), nil
+	case "searx":
+		if opts.SearxURL == "" {
+			return nil, fmt.Errorf("please set settings.search.searxurl to your SearxNG instance")
+		}
+		return NewSearxClient(opts.SearxURL), nil
+	case "brave":
+		if opts.APIKeys["brave"] == "" {
+			return nil, fmt.Errorf("please provide an API key for brave in the settings")
+		}
+		return NewBraveClient(opts.APIKeys["brave"]), nil
+	case "ddg":
+		return NewDuckDuckGoClient(), nil
+	default:
+		return nil, fmt.Errorf("unknown search backend: %s", name)
+	}
+}
+
+// siteQuery adds site: operators to a query for backends without native domain filtering
+func siteQuery(query string, opts Options) string {
+	if len(opts.IncludeDomains) > 0 {
+		sites := make([]string, len(opts.IncludeDomains))
+		for i, domain := range opts.IncludeDomains {
+			sites[i] = "site:" + domain
+		}
+		query += " " + strings.Join(sites, " OR ")
+	}
+	for _, domain := range opts.ExcludeDomains {
+		query += " -site:" + domain
+	}
+	return query
+}
+
+// filterResults applies domain filters and the result limit for backends that can't do it natively
+func filterResults(results []SearchResult, opts Options) []SearchResult {
+	filtered := make([]SearchResult, 0, len(results))
+	for _, result := range results {
+		u, err := url.Parse(result.URL)
+		if err != nil {
+			continue
+		}
+		host := strings.ToLower(u.Hostname())
+		if len(opts.IncludeDomains) > 0 && !matchesDomain(host, opts.IncludeDomains) {
+			continue
+		}
+		if matchesDomain(host, opts.ExcludeDomains) {
+			continue
+		}
+		filtered = append(filtered, result)
+	}
+
+	if opts.MaxResults > 0 {
+		filtered = TopResults(filtered, opts.MaxResults)
+	}
+	return filtered
+}
+
+// matchesDomain reports whether host is one of the domains or a subdomain of one
+func matchesDomain(host string, domains []string) bool {
+	for _, domain := range domains {
+		domain = strings.ToLower(domain)
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// timeRange returns the requested time range, converting a day count to the closest range
+func timeRange(opts Options) string {
+	if opts.TimeRange != "" || opts.Days == 0 {
+		return opts.TimeRange
+	}
+	switch {
+	case opts.Days <= 1:
+		return "day"
+	case opts.Days <= 7:
+		return "week"
+	case opts.Days <= 31:
+		return "month"
+	default:
+		return "year"
+	}
+}
+
+// IsBackend reports whether name is a search backend rather than a chat provider
+func IsBackend(name string) bool {
+	name = CanonicalName(name)
+	for _, backend := range Backends {
+		if backend == name {
+			return true
+		}
+	}
+	return false
+}
+
+// DisplayName returns the label shown on results from the named backend
+func DisplayName(name string) string {
+	switch CanonicalName(name) {
+	case "searx":
+		return "SearxNG"
+	case "brave":
+		return "Brave"
+	case "ddg":
+		return "DuckDuckGo"
+	case "fetch":
+		return "Web page"
+	default:
+		return "Tavily"
+	}
+}
@@ -0,0 +1,87 @@
+package search
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+const braveAPIEndpoint = "https://api.search.brave.com/res/v1/web/search"
+
+// BraveClient queries the Brave Search API
+type BraveClient struct {
+	apiKey string
+}
+
+func NewBraveClient(apiKey string) *BraveClient {
+	return &BraveClient{apiKey: apiKey}
+}
+
+func (c *BraveClient) Name() string {
+	return "brave"
+}
+
+// braveFreshness maps time ranges to Brave's freshness parameter
+var braveFreshness = map[string]string{"day": "pd", "week": "pw", "month": "pm", "year": "py"}
+
+func (c *BraveClient) Search(query string, opts Options) (*SearchResponse, error) {
+	params := url.Values{}
+	params.Set("q", siteQuery(query, opts))
+	if freshness := braveFreshness[timeRange(opts)]; freshness != "" {
+		params.Set("freshness", freshness)
+	}
+	if opts.MaxResults > 0 && opts.MaxResults <= 20 {
+		params.Set("count", strconv.Itoa(opts.MaxResults))
+	}
+
+	req, err := http.NewRequest("GET", braveAPIEndpoint+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Subscription-Token", c.apiKey)
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API error: %s", string(body))
+	}
+
+	var braveResp struct {
+		Web struct {
+			Results []struct {
+				Title       string `json:"title"`
+				URL         string `json:"url"`
+				Description string `json:"description"`
+			} `json:"results"`
+		} `json:"web"`
+	}
+	if err := json.Unmarshal(body, &braveResp); err != nil {
+		return nil, fmt.Errorf("error unmarshaling response: %w", err)
+	}
+
+	searchResp := &SearchResponse{Query: query}
+	for _, result := range braveResp.Web.Results {
+		searchResp.Results = append(searchResp.Results, SearchResult{
+			Title:   stripTags(result.Title),
+			URL:     result.URL,
+			Content: stripTags(result.Description),
+		})
+	}
+	searchResp.Results = filterResults(searchResp.Results, opts)
+
+	return searchResp, nil
+}
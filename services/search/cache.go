@@ -0,0 +1,76 @@
+package search
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultCacheTTL is how long cached search results are reused when no TTL is configured
+const DefaultCacheTTL = 24 * time.Hour
+
+// CacheTTL converts the configured TTL in minutes to a duration
+// 0 uses DefaultCacheTTL and a negative value disables the cache
+func CacheTTL(minutes int) time.Duration {
+	switch {
+	case minutes < 0:
+		return 0
+	case minutes == 0:
+		return DefaultCacheTTL
+	default:
+		return time.Duration(minutes) * time.Minute
+	}
+}
+
+// CacheKey returns the normalized query, domain filters and remaining options a search is cached under
+// Queries differing only in case or whitespace, or domains given in a different order, share an entry
+func CacheKey(query string, opts Options) (normalizedQuery, domains, options string) {
+	normalizedQuery = strings.ToLower(strings.Join(strings.Fields(query), " "))
+
+	var filters []string
+	for _, domain := range normalizeDomains(opts.IncludeDomains) {
+		filters = append(filters, "+"+domain)
+	}
+	for _, domain := range normalizeDomains(opts.ExcludeDomains) {
+		filters = append(filters, "-"+domain)
+	}
+	domains = strings.Join(filters, " ")
+
+	options = fmt.Sprintf("depth=%s max=%d topic=%s days=%d time=%s raw=%t images=%t",
+		opts.SearchDepth, opts.MaxResults, opts.Topic, opts.Days, opts.TimeRange,
+		opts.IncludeRawContent, opts.IncludeImages)
+
+	return normalizedQuery, domains, options
+}
+
+// normalizeDomains lowercases and sorts domains, dropping duplicates
+func normalizeDomains(domains []string) []string {
+	seen := make(map[string]bool, len(domains))
+	var normalized []string
+	for _, domain := range domains {
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		if domain == "" || seen[domain] {
+			continue
+		}
+		seen[domain] = true
+		normalized = append(normalized, domain)
+	}
+	sort.Strings(normalized)
+	return normalized
+}
+
+// FormatAge describes how long ago t was, e.g. "2h ago"
+func FormatAge(t time.Time) string {
+	age := time.Since(t)
+	switch {
+	case age < time.Minute:
+		return "just now"
+	case age < time.Hour:
+		return fmt.Sprintf("%dm ago", int(age.Minutes()))
+	case age < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(age.Hours()))
+	default:
+		return fmt.Sprintf("%dd ago", int(age.Hours()/24))
+	}
+}
@@ -0,0 +1,75 @@
+package search
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// citationRe matches numbered citations like [1], skipping ones that are already links
+var citationRe = regexp.MustCompile(`\[(\d+)\](\()?`)
+
+// numberedResultRe matches numbered results as written by FormatResult and FormatSources
+var numberedResultRe = regexp.MustCompile(`(?m)^(?:\*\*)?(\d+)\.(?:\*\*)? \*\*\[([^\]]*)\]\(([^)\s]+)\)\*\*`)
+
+// TopResults returns at most n results
+func TopResults(results []SearchResult, n int) []SearchResult {
+	if len(results) > n {
+		return results[:n]
+	}
+	return results
+}
+
+// FormatSources renders results as a numbered markdown list of sources
+func FormatSources(results []SearchResult) string {
+	var sb strings.Builder
+	sb.WriteString("### Sources\n\n")
+	for i, result := range results {
+		sb.WriteString(fmt.Sprintf("%d. **[%s](%s)**\n\n   %s\n\n", i+1, result.Title, result.URL, strings.TrimSpace(result.Content)))
+	}
+	return sb.String()
+}
+
+// FormatResult renders a single numbered search result heading
+func FormatResult(n int, result SearchResult) string {
+	return fmt.Sprintf("**%d.** **[%s](%s)**", n, result.Title, result.URL)
+}
+
+// ParseNumberedResults returns the numbered results in a formatted search message, indexed by number - 1
+func ParseNumberedResults(content string) []SearchResult {
+	var results []SearchResult
+	for _, match := range numberedResultRe.FindAllStringSubmatch(content, -1) {
+		n, err := strconv.Atoi(match[1])
+		if err != nil || n != len(results)+1 {
+			continue
+		}
+		results = append(results, SearchResult{Title: match[2], URL: match[3]})
+	}
+	return results
+}
+
+// FormatGroundingSources renders results as numbered plain text sources for the grounding prompt
+func FormatGroundingSources(results []SearchResult) string {
+	var sb strings.Builder
+	for i, result := range results {
+		sb.WriteString(fmt.Sprintf("[%d] %s\nURL: %s\n%s\n\n", i+1, result.Title, result.URL, strings.TrimSpace(result.Content)))
+	}
+	return sb.String()
+}
+
+// LinkCitations turns numbered citations such as [2] into markdown links to the matching source
+func LinkCitations(answer string, results []SearchResult) string {
+	return citationRe.ReplaceAllStringFunc(answer, func(match string) string {
+		groups := citationRe.FindStringSubmatch(match)
+		if groups[2] != "" {
+			// Already a markdown link
+			return match
+		}
+		n, err := strconv.Atoi(groups[1])
+		if err != nil || n < 1 || n > len(results) {
+			return match
+		}
+		return fmt.Sprintf("[[%d]](%s)", n, results[n-1].URL)
+	})
+}
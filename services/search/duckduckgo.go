@@ -0,0 +1,148 @@
+package search
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+const duckDuckGoEndpoint = "https://html.duckduckgo.com/html/"
+
+// DuckDuckGoClient scrapes DuckDuckGo's HTML results page, no API key required
+type DuckDuckGoClient struct {
+	endpoint string
+}
+
+func NewDuckDuckGoClient() *DuckDuckGoClient {
+	return &DuckDuckGoClient{endpoint: duckDuckGoEndpoint}
+}
+
+func (c *DuckDuckGoClient) Name() string {
+	return "ddg"
+}
+
+// duckDuckGoDates maps time ranges to DuckDuckGo's df parameter
+var duckDuckGoDates = map[string]string{"day": "d", "week": "w", "month": "m", "year": "y"}
+
+func (c *DuckDuckGoClient) Search(query string, opts Options) (*SearchResponse, error) {
+	form := url.Values{}
+	form.Set("q", siteQuery(query, opts))
+	if df := duckDuckGoDates[timeRange(opts)]; df != "" {
+		form.Set("df", df)
+	}
+
+	req, err := http.NewRequest("POST", c.endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; goatmeal)")
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("duckduckgo error: %d", resp.StatusCode)
+	}
+
+	doc, err := html.Parse(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing response: %w", err)
+	}
+
+	searchResp := &SearchResponse{Query: query}
+	searchResp.Results = filterResults(parseDuckDuckGoResults(doc), opts)
+
+	return searchResp, nil
+}
+
+// parseDuckDuckGoResults extracts results from the result__a and result__snippet elements
+func parseDuckDuckGoResults(doc *html.Node) []SearchResult {
+	var results []SearchResult
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "a" {
+			switch {
+			case hasClass(n, "result__a"):
+				results = append(results, SearchResult{
+					Title: strings.TrimSpace(textContent(n)),
+					URL:   duckDuckGoTarget(attr(n, "href")),
+				})
+				return
+			case hasClass(n, "result__snippet") && len(results) > 0:
+				results[len(results)-1].Content = strings.TrimSpace(textContent(n))
+				return
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(doc)
+
+	return results
+}
+
+// duckDuckGoTarget unwraps DuckDuckGo's redirect links to the result's real URL
+func duckDuckGoTarget(href string) string {
+	u, err := url.Parse(href)
+	if err != nil {
+		return href
+	}
+	if target := u.Query().Get("uddg"); target != "" {
+		return target
+	}
+	if u.Scheme == "" {
+		u.Scheme = "https"
+	}
+	return u.String()
+}
+
+func hasClass(n *html.Node, class string) bool {
+	for _, c := range strings.Fields(attr(n, "class")) {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+func attr(n *html.Node, name string) string {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var sb strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		sb.WriteString(textContent(child))
+	}
+	return sb.String()
+}
+
+// stripTags removes inline HTML such as <strong> highlighting from result snippets
+func stripTags(s string) string {
+	if !strings.Contains(s, "<") {
+		return html.UnescapeString(s)
+	}
+	doc, err := html.Parse(strings.NewReader(s))
+	if err != nil {
+		return s
+	}
+	return strings.TrimSpace(textContent(doc))
+}
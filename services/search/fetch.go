@@ -0,0 +1,215 @@
+package search
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	tavilyExtractEndpoint = "https://api.tavily.com/extract"
+
+	// maxPageSize limits how much of a page is downloaded
+	maxPageSize = 5 << 20
+)
+
+// Page is the readable content of a fetched web page
+type Page struct {
+	URL     string
+	Title   string
+	Content string // Markdown
+	Source  string // Name of the extractor that produced the content
+}
+
+// Extractor downloads a web page and returns its readable content
+type Extractor interface {
+	// Name returns the extractor's identifier
+	Name() string
+	// Extract fetches pageURL and converts it to markdown
+	Extract(pageURL string) (*Page, error)
+}
+
+// FetchPage returns the readable content of pageURL
+// Tavily's extract endpoint is used when a tavily key is configured, falling back
+// to downloading the page directly. Local addresses are always fetched directly
+func FetchPage(pageURL string, apiKeys map[string]string) (*Page, error) {
+	u, err := NormalizeURL(pageURL)
+	if err != nil {
+		return nil, err
+	}
+
+	direct := NewPageClient()
+	if apiKey := apiKeys["tavily"]; apiKey != "" && !isLocalHost(u.Hostname()) {
+		page, err := NewClient(apiKey).Extract(u.String())
+		if err == nil {
+			return page, nil
+		}
+		if page, directErr := direct.Extract(u.String()); directErr == nil {
+			return page, nil
+		}
+		return nil, err
+	}
+
+	return direct.Extract(u.String())
+}
+
+// NormalizeURL parses a URL typed by the user, adding https:// when the scheme is missing
+func NormalizeURL(pageURL string) (*url.URL, error) {
+	pageURL = strings.TrimSpace(pageURL)
+	if !strings.Contains(pageURL, "://") {
+		pageURL = "https://" + pageURL
+	}
+
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL %q: %w", pageURL, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported URL scheme %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid URL %q: missing host", pageURL)
+	}
+	return u, nil
+}
+
+// isLocalHost reports whether host is only reachable from this machine or network
+func isLocalHost(host string) bool {
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") || strings.HasSuffix(host, ".local") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && (ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast())
+}
+
+// PageClient downloads pages directly and extracts their content locally
+type PageClient struct {
+	client *http.Client
+}
+
+func NewPageClient() *PageClient {
+	return &PageClient{client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+func (c *PageClient) Name() string {
+	return "fetch"
+}
+
+func (c *PageClient) Extract(pageURL string) (*Page, error) {
+	req, err := http.NewRequest("GET", pageURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; goatmeal)")
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,text/plain;q=0.9,*/*;q=0.5")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error fetching %s: %s", pageURL, resp.Status)
+	}
+
+	body := io.LimitReader(resp.Body, maxPageSize)
+	page := &Page{URL: resp.Request.URL.String(), Source: c.Name()}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	switch {
+	case mediaType == "" || mediaType == "text/html" || mediaType == "application/xhtml+xml":
+		page.Title, page.Content, err = HTMLToMarkdown(body, resp.Request.URL)
+		if err != nil {
+			return nil, err
+		}
+	case strings.HasPrefix(mediaType, "text/") || mediaType == "application/json":
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return nil, fmt.Errorf("error reading response: %w", err)
+		}
+		page.Content = string(data)
+		if mediaType != "text/markdown" && mediaType != "text/plain" {
+			page.Content = "```\n" + strings.TrimRight(page.Content, "\n") + "\n```"
+		}
+	default:
+		return nil, fmt.Errorf("unsupported content type %s", mediaType)
+	}
+
+	if strings.TrimSpace(page.Content) == "" {
+		return nil, fmt.Errorf("no readable content found at %s", pageURL)
+	}
+	return page, nil
+}
+
+type extractRequest struct {
+	APIKey string   `json:"api_key"`
+	URLs   []string `json:"urls"`
+}
+
+type extractResponse struct {
+	Results []struct {
+		URL        string `json:"url"`
+		RawContent string `json:"raw_content"`
+	} `json:"results"`
+	FailedResults []struct {
+		URL   string `json:"url"`
+		Error string `json:"error"`
+	} `json:"failed_results"`
+}
+
+// Extract returns a page's content using Tavily's extract endpoint
+func (c *TavilyClient) Extract(pageURL string) (*Page, error) {
+	jsonData, err := json.Marshal(extractRequest{APIKey: c.apiKey, URLs: []string{pageURL}})
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling request: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", tavilyExtractEndpoint, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: 60 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API error: %s", string(body))
+	}
+
+	var extractResp extractResponse
+	if err := json.Unmarshal(body, &extractResp); err != nil {
+		return nil, fmt.Errorf("error unmarshaling response: %w", err)
+	}
+
+	if len(extractResp.Results) == 0 || strings.TrimSpace(extractResp.Results[0].RawContent) == "" {
+		if len(extractResp.FailedResults) > 0 {
+			return nil, fmt.Errorf("tavily extract failed: %s", extractResp.FailedResults[0].Error)
+		}
+		return nil, fmt.Errorf("tavily extract returned no content for %s", pageURL)
+	}
+
+	result := extractResp.Results[0]
+	return &Page{
+		URL:     result.URL,
+		Content: result.RawContent,
+		Source:  "tavily",
+	}, nil
+}
@@ -0,0 +1,375 @@
+package search
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// skippedElements are never part of a page's readable content
+var skippedElements = map[string]bool{
+	"script": true, "style": true, "noscript": true, "template": true,
+	"nav": true, "footer": true, "aside": true,
+	"form": true, "button": true, "iframe": true, "svg": true,
+	"canvas": true, "select": true, "input": true, "head": true,
+}
+
+// skippedRoles mark navigation and other page chrome by ARIA role
+var skippedRoles = map[string]bool{
+	"navigation": true, "banner": true, "contentinfo": true,
+	"complementary": true, "search": true, "menu": true, "dialog": true,
+}
+
+// indentMark stands in for list indentation until the markdown is cleaned up,
+// so that trimming stray whitespace doesn't flatten nested lists
+const indentMark = "\x00"
+
+var (
+	spaceRun    = regexp.MustCompile(`[ \t\r\n\f]+`)
+	codeLangTag = regexp.MustCompile(`(?:^|\s)(?:language|lang)-([\w+#-]+)`)
+)
+
+// HTMLToMarkdown extracts the main readable content of an HTML page as markdown
+// Navigation, scripts and other page chrome are dropped; headings, links, lists,
+// code blocks and tables are kept. base resolves relative links and may be nil
+func HTMLToMarkdown(r io.Reader, base *url.URL) (title, markdown string, err error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return "", "", fmt.Errorf("error parsing page: %w", err)
+	}
+
+	if n := findElement(doc, "title"); n != nil {
+		title = collapseSpace(textContent(n))
+	}
+
+	// Prefer the page's main content over the whole body
+	root := findElement(doc, "article")
+	if root == nil {
+		root = findElement(doc, "main")
+	}
+	if root == nil {
+		root = findElement(doc, "body")
+	}
+	if root == nil {
+		root = doc
+	}
+
+	c := &mdConverter{base: base}
+	markdown = strings.ReplaceAll(cleanMarkdown(c.children(root)), indentMark, " ")
+
+	if title == "" {
+		if n := findElement(root, "h1"); n != nil {
+			title = collapseSpace(textContent(n))
+		}
+	}
+
+	return title, markdown, nil
+}
+
+// mdConverter renders HTML nodes as markdown
+type mdConverter struct {
+	base *url.URL
+}
+
+func (c *mdConverter) children(n *html.Node) string {
+	var sb strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		sb.WriteString(c.node(child))
+	}
+	return sb.String()
+}
+
+func (c *mdConverter) node(n *html.Node) string {
+	switch n.Type {
+	case html.TextNode:
+		return spaceRun.ReplaceAllString(n.Data, " ")
+	case html.ElementNode:
+	default:
+		return c.children(n)
+	}
+
+	if skippedElements[n.Data] || skippedRoles[attr(n, "role")] || attr(n, "aria-hidden") == "true" || hasAttr(n, "hidden") {
+		return ""
+	}
+	// A page header is chrome, but an article's header holds its title
+	if n.Data == "header" && !inContent(n) {
+		return ""
+	}
+
+	switch n.Data {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		text := collapseSpace(c.children(n))
+		if text == "" {
+			return ""
+		}
+		level := int(n.Data[1] - '0')
+		return "\n\n" + strings.Repeat("#", level) + " " + text + "\n\n"
+
+	case "p", "div", "section", "article", "main", "figure", "figcaption", "dl", "details", "summary":
+		return "\n\n" + c.children(n) + "\n\n"
+
+	case "dt":
+		return "\n\n**" + collapseSpace(c.children(n)) + "**\n"
+
+	case "dd":
+		return "\n" + collapseSpace(c.children(n)) + "\n"
+
+	case "br":
+		return "\n"
+
+	case "hr":
+		return "\n\n---\n\n"
+
+	case "a":
+		text := collapseSpace(c.children(n))
+		href := c.resolve(attr(n, "href"))
+		if text == "" || href == "" || strings.HasPrefix(href, "javascript:") || strings.HasPrefix(href, "#") {
+			return text
+		}
+		return "[" + text + "](" + href + ")"
+
+	case "img":
+		alt := collapseSpace(attr(n, "alt"))
+		src := c.resolve(attr(n, "src"))
+		if alt == "" || src == "" {
+			return ""
+		}
+		return "![" + alt + "](" + src + ")"
+
+	case "strong", "b":
+		return wrapInline(c.children(n), "**")
+
+	case "em", "i":
+		return wrapInline(c.children(n), "_")
+
+	case "code", "kbd", "samp":
+		text := textContent(n)
+		if text == "" {
+			return ""
+		}
+		fence := "`"
+		if strings.Contains(text, "`") {
+			fence = "``"
+		}
+		return fence + text + fence
+
+	case "pre":
+		return c.codeBlock(n)
+
+	case "blockquote":
+		text := cleanMarkdown(c.children(n))
+		if text == "" {
+			return ""
+		}
+		lines := strings.Split(text, "\n")
+		for i, line := range lines {
+			lines[i] = strings.TrimRight("> "+line, " ")
+		}
+		return "\n\n" + strings.Join(lines, "\n") + "\n\n"
+
+	case "ul", "ol":
+		return c.list(n)
+
+	case "table":
+		return c.table(n)
+	}
+
+	return c.children(n)
+}
+
+// codeBlock renders a <pre> element as a fenced code block, keeping its whitespace
+func (c *mdConverter) codeBlock(n *html.Node) string {
+	lang := codeLanguage(n)
+	if code := findElement(n, "code"); code != nil && lang == "" {
+		lang = codeLanguage(code)
+	}
+
+	code := strings.Trim(textContent(n), "\n")
+	fence := "```"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+	return "\n\n" + fence + lang + "\n" + code + "\n" + fence + "\n\n"
+}
+
+// list renders a <ul> or <ol> element, indenting nested lists under their item
+func (c *mdConverter) list(n *html.Node) string {
+	var sb strings.Builder
+	sb.WriteString("\n\n")
+	index := 1
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type != html.ElementNode || child.Data != "li" {
+			continue
+		}
+
+		marker := "- "
+		if n.Data == "ol" {
+			marker = fmt.Sprintf("%d. ", index)
+			index++
+		}
+
+		item := cleanMarkdown(c.children(child))
+		lines := strings.Split(item, "\n")
+		indent := strings.Repeat(indentMark, len(marker))
+		for i, line := range lines {
+			if i == 0 {
+				lines[i] = marker + line
+			} else if line != "" {
+				lines[i] = indent + line
+			}
+		}
+		sb.WriteString(strings.Join(lines, "\n"))
+		sb.WriteString("\n")
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+// table renders a <table> element as a markdown table, using the first row as the header
+func (c *mdConverter) table(n *html.Node) string {
+	var rows [][]string
+	var walk func(*html.Node)
+	walk = func(node *html.Node) {
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type != html.ElementNode {
+				continue
+			}
+			switch child.Data {
+			case "tr":
+				var row []string
+				for cell := child.FirstChild; cell != nil; cell = cell.NextSibling {
+					if cell.Type == html.ElementNode && (cell.Data == "td" || cell.Data == "th") {
+						text := collapseSpace(c.children(cell))
+						row = append(row, strings.ReplaceAll(text, "|", `\|`))
+					}
+				}
+				if len(row) > 0 {
+					rows = append(rows, row)
+				}
+			case "table":
+				// Nested tables are flattened into their cell's text
+			default:
+				walk(child)
+			}
+		}
+	}
+	walk(n)
+
+	if len(rows) == 0 {
+		return ""
+	}
+
+	columns := 0
+	for _, row := range rows {
+		if len(row) > columns {
+			columns = len(row)
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("\n\n")
+	for i, row := range rows {
+		for len(row) < columns {
+			row = append(row, "")
+		}
+		sb.WriteString("| " + strings.Join(row, " | ") + " |\n")
+		if i == 0 {
+			sb.WriteString("|" + strings.Repeat(" --- |", columns) + "\n")
+		}
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+// resolve turns a link into an absolute URL relative to the page
+func (c *mdConverter) resolve(href string) string {
+	href = strings.TrimSpace(href)
+	if href == "" || c.base == nil {
+		return href
+	}
+	u, err := url.Parse(href)
+	if err != nil {
+		return href
+	}
+	return c.base.ResolveReference(u).String()
+}
+
+// wrapInline wraps text in a markdown emphasis marker, keeping surrounding spaces outside it
+func wrapInline(text, marker string) string {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
+		return text
+	}
+	lead := text[:strings.Index(text, trimmed)]
+	trail := text[len(lead)+len(trimmed):]
+	return lead + marker + trimmed + marker + trail
+}
+
+// codeLanguage reads the language from a language-xxx or lang-xxx class
+func codeLanguage(n *html.Node) string {
+	if m := codeLangTag.FindStringSubmatch(attr(n, "class")); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// cleanMarkdown trims trailing spaces and collapses blank lines outside code blocks
+func cleanMarkdown(text string) string {
+	lines := strings.Split(text, "\n")
+	cleaned := make([]string, 0, len(lines))
+	inFence := false
+	for _, line := range lines {
+		if strings.HasPrefix(strings.TrimLeft(line, " "+indentMark), "```") {
+			inFence = !inFence
+			cleaned = append(cleaned, strings.TrimSpace(line))
+			continue
+		}
+		if !inFence {
+			line = strings.TrimSpace(line)
+			if line == "" && len(cleaned) > 0 && cleaned[len(cleaned)-1] == "" {
+				continue
+			}
+		}
+		cleaned = append(cleaned, line)
+	}
+	return strings.TrimSpace(strings.Join(cleaned, "\n"))
+}
+
+// inContent reports whether n is inside the page's article or main content
+func inContent(n *html.Node) bool {
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p.Type == html.ElementNode && (p.Data == "article" || p.Data == "main") {
+			return true
+		}
+	}
+	return false
+}
+
+func collapseSpace(s string) string {
+	return strings.TrimSpace(spaceRun.ReplaceAllString(s, " "))
+}
+
+func findElement(n *html.Node, name string) *html.Node {
+	if n.Type == html.ElementNode && n.Data == name {
+		return n
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if found := findElement(child, name); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func hasAttr(n *html.Node, name string) bool {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return true
+		}
+	}
+	return false
+}
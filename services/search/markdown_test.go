@@ -0,0 +1,200 @@
+package search
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testPage = `<!DOCTYPE html>
+<html>
+<head>
+	<title>Goroutines explained</title>
+	<script>var tracking = "analytics";</script>
+	<style>body { color: red; }</style>
+</head>
+<body>
+	<header><a href="/">Site logo</a></header>
+	<nav><a href="/blog">Blog</a> <a href="/about">About</a></nav>
+	<article>
+		<header><h1>Goroutines   explained</h1></header>
+		<p>Start one with the <code>go</code> keyword. See <a href="/docs/spec">the spec</a>.</p>
+		<pre><code class="language-go">func main() {
+	go work()
+
+
+	wait()
+}</code></pre>
+		<table>
+			<tr><th>Name</th><th>Cost</th></tr>
+			<tr><td>goroutine</td><td>2 KB</td></tr>
+			<tr><td>thread</td><td>1 | 2 MB</td></tr>
+		</table>
+		<script>console.log("inline")</script>
+	</article>
+	<footer>Copyright</footer>
+</body>
+</html>`
+
+func TestFetchPage(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/article", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, testPage)
+	})
+	mux.HandleFunc("/notes.txt", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		fmt.Fprint(w, "plain notes")
+	})
+	mux.HandleFunc("/data.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"a": 1}`)
+	})
+	mux.HandleFunc("/image.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		fmt.Fprint(w, "\x89PNG")
+	})
+	mux.HandleFunc("/empty", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<html><body><nav>Only a menu</nav></body></html>")
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		path    string
+		title   string
+		want    []string
+		notWant []string
+		err     string
+	}{
+		{
+			name:  "html article",
+			path:  "/article",
+			title: "Goroutines explained",
+			want: []string{
+				"# Goroutines explained",
+				"Start one with the `go` keyword. See [the spec](" + server.URL + "/docs/spec).",
+				"```go\nfunc main() {\n\tgo work()\n\n\n\twait()\n}\n```",
+				"| Name | Cost |\n| --- | --- |\n| goroutine | 2 KB |\n| thread | 1 \\| 2 MB |",
+			},
+			notWant: []string{"Site logo", "Blog", "analytics", "inline", "color: red", "Copyright"},
+		},
+		{
+			name: "plain text",
+			path: "/notes.txt",
+			want: []string{"plain notes"},
+		},
+		{
+			name: "json",
+			path: "/data.json",
+			want: []string{"```\n{\"a\": 1}\n```"},
+		},
+		{
+			name: "unsupported content type",
+			path: "/image.png",
+			err:  "unsupported content type image/png",
+		},
+		{
+			name: "no readable content",
+			path: "/empty",
+			err:  "no readable content",
+		},
+		{
+			name: "not found",
+			path: "/missing",
+			err:  "404 Not Found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Local addresses are fetched directly even with a tavily key
+			page, err := FetchPage(server.URL+tt.path, map[string]string{"tavily": "unused"})
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("got error %v, want one containing %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if page.Source != "fetch" {
+				t.Errorf("got source %q, want fetch", page.Source)
+			}
+			if page.Title != tt.title {
+				t.Errorf("got title %q, want %q", page.Title, tt.title)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(page.Content, want) {
+					t.Errorf("content is missing %q:\n%s", want, page.Content)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(page.Content, notWant) {
+					t.Errorf("content contains %q:\n%s", notWant, page.Content)
+				}
+			}
+		})
+	}
+}
+
+func TestHTMLToMarkdown(t *testing.T) {
+	tests := []struct {
+		name     string
+		html     string
+		title    string
+		markdown string
+	}{
+		{
+			name:     "page header is dropped",
+			html:     `<body><header><a href="/">Site name</a></header><p>Body text</p></body>`,
+			markdown: "Body text",
+		},
+		{
+			name:     "article header is kept",
+			html:     `<body><header>Site name</header><article><header><h1>Post title</h1></header><p>Body text</p></article></body>`,
+			title:    "Post title",
+			markdown: "# Post title\n\nBody text",
+		},
+		{
+			name:     "main header is kept",
+			html:     `<body><main><header><h2>Section</h2></header><p>Body text</p></main></body>`,
+			markdown: "## Section\n\nBody text",
+		},
+		{
+			name:     "blank lines collapse outside code blocks",
+			html:     `<body><p>One</p><div><div></div></div><p>Two</p><pre>a` + "\n\n\n\n" + `b</pre></body>`,
+			markdown: "One\n\nTwo\n\n```\na\n\n\n\nb\n```",
+		},
+		{
+			name:     "nested list",
+			html:     `<body><ul><li>One<ol><li>Nested</li></ol></li><li>Two</li></ul></body>`,
+			markdown: "- One\n\n  1. Nested\n- Two",
+		},
+		{
+			name:     "hidden and navigation roles",
+			html:     `<body><div role="navigation">Menu</div><p hidden>Hidden</p><p aria-hidden="true">Icon</p><p>Shown</p></body>`,
+			markdown: "Shown",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			title, markdown, err := HTMLToMarkdown(strings.NewReader(tt.html), nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if title != tt.title {
+				t.Errorf("got title %q, want %q", title, tt.title)
+			}
+			if markdown != tt.markdown {
+				t.Errorf("got markdown\n%q\nwant\n%q", markdown, tt.markdown)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package search
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Options controls how a search is run
+// Backends ignore the options they don't support
+type Options struct {
+	IncludeDomains    []string
+	ExcludeDomains    []string
+	SearchDepth       string // "basic" or "advanced"
+	MaxResults        int
+	Topic             string // "general" or "news"
+	Days              int    // Only results from the last n days, news topic only
+	TimeRange         string // "day", "week", "month" or "year"
+	IncludeRawContent bool
+	IncludeImages     bool
+}
+
+// Preset is a named set of filters applied with @name in a query
+type Preset struct {
+	IncludeDomains []string
+	ExcludeDomains []string
+	Topic          string
+}
+
+var timeRanges = map[string]bool{"day": true, "week": true, "month": true, "year": true}
+
+// ParseQuery splits a /web query into the search terms and options
+// Supported syntax, starting from defaults:
+//
+//	+domain.com       only search domain.com
+//	-domain.com       exclude domain.com
+//	--news            search news, --general for general topics
+//	--days N          results from the last N days
+//	--time RANGE      results from the last day, week, month or year
+//	--deep            advanced search depth, --basic for basic
+//	--max N           return at most N results
+//	--raw             include the raw page content
+//	--images          include related images
+//	@name             apply the named preset
+func ParseQuery(input string, defaults Options, presets map[string]Preset) (string, Options, error) {
+	opts := defaults
+	opts.IncludeDomains = append([]string(nil), defaults.IncludeDomains...)
+	opts.ExcludeDomains = append([]string(nil), defaults.ExcludeDomains...)
+
+	var terms []string
+	fields := strings.Fields(input)
+	for i := 0; i < len(fields); i++ {
+		field := fields[i]
+
+		// intArg reads the numeric value following a flag
+		intArg := func() (int, error) {
+			if i+1 >= len(fields) {
+				return 0, fmt.Errorf("%s needs a number", field)
+			}
+			i++
+			n, err := strconv.Atoi(fields[i])
+			if err != nil || n < 0 {
+				return 0, fmt.Errorf("%s needs a number, got %q", field, fields[i])
+			}
+			return n, nil
+		}
+
+		switch {
+		case strings.HasPrefix(field, "--"):
+			switch field {
+			case "--news":
+				opts.Topic = "news"
+			case "--general":
+				opts.Topic = "general"
+			case "--deep", "--advanced":
+				opts.SearchDepth = "advanced"
+			case "--basic":
+				opts.SearchDepth = "basic"
+			case "--raw":
+				opts.IncludeRawContent = true
+			case "--images":
+				opts.IncludeImages = true
+			case "--days":
+				n, err := intArg()
+				if err != nil {
+					return "", Options{}, err
+				}
+				opts.Days = n
+			case "--max", "--max-results":
+				n, err := intArg()
+				if err != nil {
+					return "", Options{}, err
+				}
+				opts.MaxResults = n
+			case "--time", "--time-range":
+				if i+1 >= len(fields) || !timeRanges[fields[i+1]] {
+					return "", Options{}, fmt.Errorf("%s needs one of day, week, month or year", field)
+				}
+				i++
+				opts.TimeRange = fields[i]
+			default:
+				return "", Options{}, fmt.Errorf("unknown search option %s", field)
+			}
+
+		case strings.HasPrefix(field, "@") && len(field) > 1:
+			name := strings.ToLower(strings.TrimPrefix(field, "@"))
+			preset, ok := presets[name]
+			if !ok {
+				return "", Options{}, fmt.Errorf("unknown search preset @%s", name)
+			}
+			opts.IncludeDomains = append(opts.IncludeDomains, preset.IncludeDomains...)
+			opts.ExcludeDomains = append(opts.ExcludeDomains, preset.ExcludeDomains...)
+			if preset.Topic != "" {
+				opts.Topic = preset.Topic
+			}
+
+		case strings.HasPrefix(field, "+"):
+			// Allow +a.com+b.com as well as +a.com +b.com
+			for _, domain := range strings.Split(field, "+") {
+				if domain != "" {
+					opts.IncludeDomains = append(opts.IncludeDomains, domain)
+				}
+			}
+
+		case strings.HasPrefix(field, "-") && strings.Contains(field, "."):
+			opts.ExcludeDomains = append(opts.ExcludeDomains, strings.TrimPrefix(field, "-"))
+
+		default:
+			terms = append(terms, field)
+		}
+	}
+
+	return strings.Join(terms, " "), opts, nil
+}
+
+// Describe summarizes the non-default options for display under the query
+func (o Options) Describe() string {
+	var parts []string
+	if len(o.IncludeDomains) > 0 {
+		parts = append(parts, "Domains: "+strings.Join(o.IncludeDomains, ", "))
+	}
+	if len(o.ExcludeDomains) > 0 {
+		parts = append(parts, "Excluding: "+strings.Join(o.ExcludeDomains, ", "))
+	}
+	if o.Topic == "news" {
+		parts = append(parts, "Topic: news")
+	}
+	if o.Days > 0 {
+		parts = append(parts, fmt.Sprintf("Last %d days", o.Days))
+	}
+	if o.TimeRange != "" {
+		parts = append(parts, "Time range: "+o.TimeRange)
+	}
+	if o.SearchDepth == "advanced" {
+		parts = append(parts, "Depth: advanced")
+	}
+	return strings.Join(parts, "\n")
+}
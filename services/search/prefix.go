@@ -7,6 +7,8 @@ func StripPrefix(content string) string {
     prefixes := []string{
         "🔍 Searching for: ",
         "🔍+ Enhanced search: ",
+        "🔎 Ask web: ",
+        "🌐 Fetch: ",
     }
     
     for _, prefix := range prefixes {
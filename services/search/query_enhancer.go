@@ -18,12 +18,15 @@ const (
 )
 
 type QueryEnhancer struct {
-    apiKey string
+    apiKey   string
+    location *location.Resolver
 }
 
-func NewQueryEnhancer(apiKey string) *QueryEnhancer {
+// NewQueryEnhancer creates an enhancer that adds context from the given location resolver to web searches
+func NewQueryEnhancer(apiKey string, resolver *location.Resolver) *QueryEnhancer {
     return &QueryEnhancer{
-        apiKey: apiKey,
+        apiKey:   apiKey,
+        location: resolver,
     }
 }
 
@@ -41,7 +44,7 @@ func (qe *QueryEnhancer) Enhance(query string, enhanceType EnhanceType) (string,
     var fullPrompt string
     switch enhanceType {
     case WebSearch:
-        locationInfo := location.GetFormattedLocationAndTime()
+        locationInfo := qe.location.FormattedLocationAndTime()
         fullPrompt = prompts.GetEnhanceSearchPrompt(locationInfo, query)
     case Programming:
         fullPrompt = prompts.GetEnhanceProgrammingPrompt(query)
@@ -0,0 +1,86 @@
+package search
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// SearxClient queries a self-hosted SearxNG instance through its JSON API
+// The instance must have "json" enabled under search.formats in settings.yml
+type SearxClient struct {
+	baseURL string
+}
+
+func NewSearxClient(baseURL string) *SearxClient {
+	return &SearxClient{baseURL: strings.TrimRight(baseURL, "/")}
+}
+
+func (c *SearxClient) Name() string {
+	return "searx"
+}
+
+func (c *SearxClient) Search(query string, opts Options) (*SearchResponse, error) {
+	params := url.Values{}
+	params.Set("q", siteQuery(query, opts))
+	params.Set("format", "json")
+	if opts.Topic == "news" {
+		params.Set("categories", "news")
+	}
+	if tr := timeRange(opts); tr != "" {
+		params.Set("time_range", tr)
+	}
+
+	req, err := http.NewRequest("GET", c.baseURL+"/search?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("searx error: %d %s", resp.StatusCode, string(body))
+	}
+
+	var searxResp struct {
+		Query   string   `json:"query"`
+		Answers []string `json:"answers"`
+		Results []struct {
+			Title   string `json:"title"`
+			URL     string `json:"url"`
+			Content string `json:"content"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(body, &searxResp); err != nil {
+		return nil, fmt.Errorf("error unmarshaling response: %w", err)
+	}
+
+	searchResp := &SearchResponse{Query: query}
+	if len(searxResp.Answers) > 0 {
+		searchResp.Answer = strings.Join(searxResp.Answers, "\n\n")
+	}
+	for _, result := range searxResp.Results {
+		searchResp.Results = append(searchResp.Results, SearchResult{
+			Title:   result.Title,
+			URL:     result.URL,
+			Content: result.Content,
+		})
+	}
+	searchResp.Results = filterResults(searchResp.Results, opts)
+
+	return searchResp, nil
+}
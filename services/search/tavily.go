@@ -12,23 +12,51 @@ import (
 const tavilyAPIEndpoint = "https://api.tavily.com/search"
 
 type SearchRequest struct {
-	APIKey         string   `json:"api_key"`
-	Query          string   `json:"query"`
-	IncludeAnswer  bool     `json:"include_answer"`
-	IncludeDomains []string `json:"include_domains,omitempty"`
+	APIKey            string   `json:"api_key"`
+	Query             string   `json:"query"`
+	IncludeAnswer     bool     `json:"include_answer"`
+	IncludeDomains    []string `json:"include_domains,omitempty"`
+	ExcludeDomains    []string `json:"exclude_domains,omitempty"`
+	SearchDepth       string   `json:"search_depth,omitempty"`
+	MaxResults        int      `json:"max_results,omitempty"`
+	Topic             string   `json:"topic,omitempty"`
+	Days              int      `json:"days,omitempty"`
+	TimeRange         string   `json:"time_range,omitempty"`
+	IncludeRawContent bool     `json:"include_raw_content,omitempty"`
+	IncludeImages     bool     `json:"include_images,omitempty"`
 }
 
 type SearchResponse struct {
 	Query   string         `json:"query"`
 	Answer  interface{}    `json:"answer"`
 	Results []SearchResult `json:"results"`
+	Images  []SearchImage  `json:"images"`
 	ResponseTime float64   `json:"response_time"`
 }
 
 type SearchResult struct {
-	Title   string `json:"title"`
-	URL     string `json:"url"`
-	Content string `json:"content"`
+	Title      string `json:"title"`
+	URL        string `json:"url"`
+	Content    string `json:"content"`
+	RawContent string `json:"raw_content,omitempty"`
+}
+
+// SearchImage is an image returned when include_images is set
+// Tavily returns plain URLs, or objects when image descriptions are requested
+type SearchImage struct {
+	URL         string `json:"url"`
+	Description string `json:"description,omitempty"`
+}
+
+// UnmarshalJSON accepts both an image URL string and an image object
+func (i *SearchImage) UnmarshalJSON(data []byte) error {
+	var url string
+	if err := json.Unmarshal(data, &url); err == nil {
+		i.URL = url
+		return nil
+	}
+	type image SearchImage
+	return json.Unmarshal(data, (*image)(i))
 }
 
 type TavilyClient struct {
@@ -39,12 +67,29 @@ func NewClient(apiKey string) *TavilyClient {
 	return &TavilyClient{apiKey: apiKey}
 }
 
-func (c *TavilyClient) Search(query string, domains []string) (*SearchResponse, error) {
+func (c *TavilyClient) Name() string {
+	return "tavily"
+}
+
+func (c *TavilyClient) Search(query string, opts Options) (*SearchResponse, error) {
 	reqBody := SearchRequest{
-		APIKey:         c.apiKey,
-		Query:          query,
-		IncludeAnswer:  true,
-		IncludeDomains: domains,
+		APIKey:            c.apiKey,
+		Query:             query,
+		IncludeAnswer:     true,
+		IncludeDomains:    opts.IncludeDomains,
+		ExcludeDomains:    opts.ExcludeDomains,
+		SearchDepth:       opts.SearchDepth,
+		MaxResults:        opts.MaxResults,
+		Topic:             opts.Topic,
+		TimeRange:         opts.TimeRange,
+		IncludeRawContent: opts.IncludeRawContent,
+		IncludeImages:     opts.IncludeImages,
+	}
+	// Tavily only accepts days for the news topic, other topics get the closest time range
+	if opts.Topic == "news" {
+		reqBody.Days = opts.Days
+	} else {
+		reqBody.TimeRange = timeRange(opts)
 	}
 	jsonData, err := json.Marshal(reqBody)
 	if err != nil {
@@ -57,6 +57,7 @@ func NewAPIKeySettings(cfg *config.Config) APIKeySettings {
 		APIKeyMenuItem{provider: "deepseek", hasKey: cfg.APIKeys["deepseek"] != ""},
 		APIKeyMenuItem{provider: "groq", hasKey: cfg.APIKeys["groq"] != ""},
 		APIKeyMenuItem{provider: "tavily", hasKey: cfg.APIKeys["tavily"] != ""},
+		APIKeyMenuItem{provider: "brave", hasKey: cfg.APIKeys["brave"] != ""},
 	}
 
 	l := list.New(items, list.NewDefaultDelegate(), 0, 0)
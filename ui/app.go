@@ -3,11 +3,13 @@ package ui
 import (
 	"context"
 	"fmt"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/atotto/clipboard"
+	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
@@ -16,12 +18,10 @@ import (
 	"github.com/tedfulk/goatmeal/config"
 	"github.com/tedfulk/goatmeal/database"
 	"github.com/tedfulk/goatmeal/services/providers"
-	"github.com/tedfulk/goatmeal/services/providers/anthropic"
-	"github.com/tedfulk/goatmeal/services/providers/gemini"
-	"github.com/tedfulk/goatmeal/services/providers/ollama"
 	"github.com/tedfulk/goatmeal/services/search"
 	"github.com/tedfulk/goatmeal/ui/theme"
 	"github.com/tedfulk/goatmeal/utils/editor"
+	"github.com/tedfulk/goatmeal/utils/location"
 	"github.com/tedfulk/goatmeal/utils/prompts"
 )
 
@@ -53,8 +53,20 @@ type App struct {
 	currentConversationID string
 	searchDomains []string
 	helpView          *HelpView
+	linksView         *LinksView
+	importView        *ImportView
+	exportSettings    ExportSettings
+	searchPresetSettings SearchPresetSettings
 	totalCodeBlocks int
 	queryEnhancer *search.QueryEnhancer
+	historySummary string
+	summarizedTurns int
+
+	// Index of the message being edited with /edit, -1 when not editing
+	editIndex int
+
+	// Shown in the status bar once the UI starts
+	startupNotice string
 }
 
 func NewApp(cfg *config.Config, db *database.DB) *App {
@@ -83,14 +95,27 @@ func NewApp(cfg *config.Config, db *database.DB) *App {
 		apiKeySettings:   NewAPIKeySettings(cfg),
 		
 		systemPromptSettings: NewSystemPromptSettings(cfg),
+		searchPresetSettings: NewSearchPresetSettings(cfg),
 		conversationList: NewConversationListView(db, cfg),
 		helpView:          NewHelpView(),
+		linksView:         NewLinksView(),
+		importView:        NewImportView(db),
+		exportSettings:    NewExportSettings(cfg),
 		totalCodeBlocks: 0,
-		queryEnhancer: search.NewQueryEnhancer(cfg.APIKeys["groq"]),
+		editIndex:       -1,
+		queryEnhancer: search.NewQueryEnhancer(cfg.APIKeys["groq"], newLocationResolver(cfg)),
 	}
 }
 
+// SetStartupNotice shows notice in the status bar when the UI starts, e.g. why a startup task was skipped
+func (a *App) SetStartupNotice(notice string) {
+	a.startupNotice = notice
+}
+
 func (a *App) Init() tea.Cmd {
+	if a.startupNotice != "" {
+		a.statusBar.SetError(a.startupNotice)
+	}
 	return tea.EnableMouseCellMotion
 }
 
@@ -113,6 +138,15 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		a.currentView = "settings"
 		return a, nil
 
+	case OpenConversationMsg:
+		a.handleOpenConversation(msg)
+		return a, nil
+	case importDoneMsg:
+		// Delivered even after leaving the import view
+		a.importView, _ = a.importView.Update(msg)
+		a.refreshConversationList()
+		return a, nil
+
 	case ThemeChangeMsg:
 		a.conversationWindow.Style = lipgloss.NewStyle().
 			BorderStyle(lipgloss.RoundedBorder()).
@@ -150,11 +184,7 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			a.refreshConversationList()
 		case "ctrl+t":
 			// Start a new conversation
-			a.messages = make([]Message, 0)
-			a.nextMessageID = 1
-			a.currentConversationID = ""
-			a.totalCodeBlocks = 0  // Reset code block counter
-			a.statusBar.SetConversationTitle("New Conversation")
+			a.resetConversation()
 			a.currentView = "chat"
 			a.showMenu = false
 			a.updateConversationView()
@@ -164,23 +194,63 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				a.currentView = "chat"
 				return a, nil
 			}
-			if a.currentView == "conversations" {
-				// Let the conversation list handle its own escape key
+			if a.currentView == "links" {
+				if a.linksView.list.FilterState() == list.Unfiltered {
+					a.currentView = "chat"
+					return a, nil
+				}
+				// Let the list clear its filter first
+				break
+			}
+			if a.currentView == "conversations" || a.currentView == "import" {
+				// Let the conversation list and import view handle their own escape key
 				break
-			} else if a.currentView == "glamour" || a.currentView == "username" || a.currentView == "apikeys" || a.currentView == "systemprompts" || a.currentView == "theme" || a.currentView == "model" {
+			} else if a.currentView == "glamour" || a.currentView == "username" || a.currentView == "apikeys" || a.currentView == "systemprompts" || a.currentView == "theme" || a.currentView == "model" || a.currentView == "searchpresets" || a.currentView == "exportdir" {
 				// Let these views handle their own escape key
 				break
 			} else if a.currentView == "settings" {
 				a.currentView = "chat"
+			} else if a.editIndex >= 0 {
+				a.cancelEdit()
 			} else if a.showMenu {
 				a.showMenu = false
 			}
 			return a, nil
 		case "enter":
+			if a.currentView == "conversations" || a.currentView == "import" || a.currentView == "exportdir" {
+				// The conversation list uses enter to continue a conversation, the import view to import and the export directory view to save
+				break
+			}
 			input := a.input.Value()
 			if strings.HasPrefix(input, "/") {
 				input = strings.TrimPrefix(input, "/")
-				if strings.HasPrefix(input, "o") {
+				a.editIndex = -1 // Commands are never sent as an edit
+				if strings.HasPrefix(input, "open ") {
+					// Handle opening a search result or URL in the browser
+					a.openResult(strings.TrimPrefix(input, "open "))
+				} else if input == "links" {
+					// Handle listing the links in the conversation
+					a.linksView.SetLinks(conversationLinks(a.messages))
+					a.currentView = "links"
+				} else if strings.HasPrefix(input, "edit ") {
+					// Handle editing an earlier message, which keeps the message in the input
+					a.startEdit(strings.TrimPrefix(input, "edit "))
+					return a, nil
+				} else if input == "regen" || strings.HasPrefix(input, "regen ") {
+					// Handle answering the last message again as another version of the reply
+					cmd := a.regenerate(strings.TrimPrefix(input, "regen"))
+					a.input.Reset()
+					return a, cmd
+				} else if input == "pin" || input == "unpin" {
+					// Handle keeping the conversation at the top of the list and out of cleanup
+					a.pinConversation(input == "pin")
+				} else if input == "tag" || strings.HasPrefix(input, "tag ") {
+					// Handle showing, adding and removing the conversation's tags
+					a.tagCommand(strings.TrimPrefix(input, "tag"))
+				} else if strings.HasPrefix(input, "v") && len(input) > 1 && input[1] >= '0' && input[1] <= '9' {
+					// Handle switching to another version of a message
+					a.switchVariant(strings.TrimPrefix(input, "v"))
+				} else if strings.HasPrefix(input, "o") {
 					// Handle message opening to default editor
 					if msgNum, err := strconv.Atoi(strings.TrimPrefix(input, "o")); err == nil {
 						for _, m := range a.messages {
@@ -252,6 +322,12 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 							}
 						}
 					}
+				} else if strings.HasPrefix(input, "fetch ") {
+					// Handle fetching a single page
+					return a, a.fetchPage(strings.TrimPrefix(input, "fetch "))
+				} else if strings.HasPrefix(input, "ask-web") {
+					// Handle search-grounded answers
+					return a, a.askWeb(strings.TrimPrefix(input, "ask-web"))
 				} else if strings.HasPrefix(input, "web") || strings.HasPrefix(input, "webe") || strings.HasPrefix(input, "epq") {
 					// Handle web search and programming query enhancement
 					var query string
@@ -259,35 +335,41 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					var enhanceType search.EnhanceType
 					
 					if strings.HasPrefix(input, "webe") {
-						query = strings.TrimSpace(strings.TrimPrefix(input, "webe"))
+						query = strings.TrimPrefix(input, "webe")
 						isEnhanced = true
 						enhanceType = search.WebSearch
 					} else if strings.HasPrefix(input, "epq") {
-						query = strings.TrimSpace(strings.TrimPrefix(input, "epq"))
+						query = strings.TrimPrefix(input, "epq")
 						isEnhanced = true
 						enhanceType = search.Programming
 					} else {
-						query = strings.TrimSpace(strings.TrimPrefix(input, "web"))
+						query = strings.TrimPrefix(input, "web")
 						enhanceType = search.WebSearch
 					}
 
-					if query != "" {
-						// Check for domain inclusions (marked with +) for web searches
-						var domains []string
-						if enhanceType == search.WebSearch {
-							parts := strings.Split(query, "+")
-							query = strings.TrimSpace(parts[0])
-							
-							// If there are additional parts, they are domains
-							if len(parts) > 1 {
-								for _, domain := range parts[1:] {
-									domain = strings.TrimSpace(domain)
-									if domain != "" {
-										domains = append(domains, domain)
-									}
-								}
-							}
+					// Pick the search backend, either from /web@name or the configured default
+					// Only an @ directly after the command names a backend
+					// /web! and /webe! skip cached results
+					backendName := a.config.Settings.Search.Backend
+					var bypassCache bool
+					if enhanceType == search.WebSearch {
+						query, bypassCache = strings.CutPrefix(query, "!")
+						backendName, query = splitBackend(query, backendName)
+					}
+					query = strings.TrimSpace(query)
+
+					// Parse domain filters and search options for web searches
+					var opts search.Options
+					if enhanceType == search.WebSearch {
+						var err error
+						query, opts, err = search.ParseQuery(query, a.searchOptions(), a.searchPresets())
+						if err != nil {
+							a.statusBar.SetError(fmt.Sprintf("Invalid search: %v", err))
+							return a, nil
 						}
+					}
+
+					if query != "" {
 
 						// Create message prefix based on mode
 						var searchMsg string
@@ -312,8 +394,11 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 							}
 						}
 						
-						if len(domains) > 0 {
-							searchMsg += fmt.Sprintf("\nDomains: %s", strings.Join(domains, ", "))
+						if description := opts.Describe(); description != "" {
+							searchMsg += "\n" + description
+						}
+						if enhanceType == search.WebSearch && backendOrDefault(backendName) != search.DefaultBackend {
+							searchMsg += fmt.Sprintf("\nBackend: %s", search.DisplayName(backendName))
 						}
 
 						// Create and store user message
@@ -341,8 +426,15 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 									a.statusBar.SetLoading(false)
 								}()
 
-								tavilyClient := search.NewClient(a.config.APIKeys["tavily"])
-								searchResp, err := tavilyClient.Search(query, domains)
+								var searchResp *search.SearchResponse
+								var cachedAt time.Time
+								backend, err := search.NewBackend(backendName, search.BackendOptions{
+									APIKeys:  a.config.APIKeys,
+									SearxURL: a.config.Settings.Search.SearxURL,
+								})
+								if err == nil {
+									searchResp, cachedAt, err = a.cachedSearch(backend, query, opts, bypassCache)
+								}
 								
 								var response string
 								if err != nil {
@@ -361,65 +453,36 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 									sb.WriteString("### Search Results\n\n")
 									
 									// Show individual results
-									for _, result := range searchResp.Results {
-										sb.WriteString(fmt.Sprintf("**[%s](%s)**\n\n%s\n\n---\n\n", 
-											result.Title, result.URL, result.Content))
+									for i, result := range searchResp.Results {
+										sb.WriteString(fmt.Sprintf("%s\n\n%s\n\n", 
+											search.FormatResult(i+1, result), result.Content))
+										if result.RawContent != "" {
+											sb.WriteString(fmt.Sprintf("<details>\n\n%s\n\n</details>\n\n", truncateText(result.RawContent, rawContentLimit)))
+										}
+										sb.WriteString("---\n\n")
+									}
+
+									if len(searchResp.Images) > 0 {
+										sb.WriteString("### Images\n\n")
+										for i, image := range searchResp.Images {
+											label := image.Description
+											if label == "" {
+												label = fmt.Sprintf("Image %d", i+1)
+											}
+											sb.WriteString(fmt.Sprintf("- [%s](%s)\n", label, image.URL))
+										}
 									}
 									response = sb.String()
 								}
 								
 								// Create and store search result message
 								searchMsg := NewMessage(a.nextMessageID, SearchMessage, response, a.config, a.getNextCodeBlockNumber)
+								searchMsg.Source = searchSource(backendName, cachedAt)
 								a.messages = append(a.messages, searchMsg)
 								a.nextMessageID++
 								
-								// Save to database if we have a current conversation
-								if a.currentConversationID != "" {
-									if len(a.messages) == 2 { // First user message + first search response
-										// Save the entire conversation
-										conv := &database.Conversation{
-											ID:        a.currentConversationID,
-											Title:     a.statusBar.conversationTitle,
-											Provider:  "tavily",
-											Model:     "search",
-											CreatedAt: time.Now(),
-											UpdatedAt: time.Now(),
-											Messages:  make([]database.Message, len(a.messages)),
-										}
-
-										// Convert UI messages to database messages
-										for i, msg := range a.messages {
-											role := "user"
-											if msg.Type == SearchMessage {
-												role = "search"
-											}
-											conv.Messages[i] = database.Message{
-												ID:             uuid.New().String(),
-												ConversationID: conv.ID,
-												Role:           role,
-												Content:        msg.Content,
-												CreatedAt:      msg.Timestamp,
-											}
-										}
-
-										if err := a.db.SaveConversation(conv); err != nil {
-											fmt.Printf("Error saving conversation: %v\n", err)
-										}
-										a.refreshConversationList()
-									} else {
-										// Add just the new message for subsequent messages
-										dbMsg := &database.Message{
-											ID:             uuid.New().String(),
-											ConversationID: a.currentConversationID,
-											Role:           "search",
-											Content:        response,
-											CreatedAt:      time.Now(),
-										}
-										if err := a.db.AddMessage(dbMsg); err != nil {
-											fmt.Printf("Error adding search message: %v\n", err)
-										}
-									}
-								}
+								// Save the exchange to the database
+								a.saveExchange(2, backendOrDefault(backendName), "search")
 								
 								a.updateConversationView()
 							}()
@@ -437,112 +500,21 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 									a.statusBar.SetLoading(false)
 								}()
 
-								// Get provider instance based on current provider
-								providerName := strings.ToLower(a.config.CurrentProvider)
-								apiKey := a.config.APIKeys[providerName]
-
-								var response string
-								var err error
-
-								if apiKey == "" {
-									response = fmt.Sprintf("Error: Please provide an API key for %s in the settings", a.config.CurrentProvider)
-								} else {
-									// Build conversation history
-									var conversationHistory []string
-									for _, msg := range a.messages[:len(a.messages)-1] { // Exclude the message we just added
-										if msg.Type == UserMessage {
-											conversationHistory = append(conversationHistory, "User: "+msg.Content)
-										} else if msg.Type == ProviderMessage {
-											conversationHistory = append(conversationHistory, "Assistant: "+msg.Content)
-										}
-									}
-									
-									// Add current message
-									messageToSend := input
-									if enhanceType == search.Programming {
-										messageToSend = query
-									}
-									conversationHistory = append(conversationHistory, "User: "+messageToSend)
-									
-									// Join history with newlines
-									fullPrompt := strings.Join(conversationHistory, "\n")
-
-									switch providerName {
-									case "anthropic":
-										provider := anthropic.NewProvider(apiKey)
-										response, err = provider.SendMessage(context.Background(), fullPrompt, a.config.CurrentSystemPrompt, a.config.CurrentModel)
-									case "gemini":
-										provider := gemini.NewProvider(apiKey)
-										response, err = provider.SendMessage(context.Background(), fullPrompt, a.config.CurrentSystemPrompt, a.config.CurrentModel)
-									case "ollama":
-										provider := ollama.NewProvider(apiKey)
-										response, err = provider.SendMessage(context.Background(), fullPrompt, a.config.CurrentSystemPrompt, a.config.CurrentModel)
-									default:
-										cfg := providers.OpenAICompatibleConfig{
-											Name:   providerName,
-											APIKey: apiKey,
-										}
-										provider := providers.NewOpenAICompatibleProvider(cfg)
-										response, err = provider.SendMessage(context.Background(), fullPrompt, a.config.CurrentSystemPrompt, a.config.CurrentModel)
-									}
-
-									if err != nil {
-										response = "Error: " + err.Error()
-									}
+								// Add current message
+								messageToSend := input
+								if enhanceType == search.Programming {
+									messageToSend = query
 								}
+								response, provenance, _ := a.sendToProvider(messageToSend)
 
 								// Create and store provider message
 								providerMsg := NewMessage(a.nextMessageID, ProviderMessage, response, a.config, a.getNextCodeBlockNumber)
+								providerMsg.Provenance = provenance
 								a.messages = append(a.messages, providerMsg)
 								a.nextMessageID++
 
-								// Save to database if we have a current conversation
-								if a.currentConversationID != "" {
-									if len(a.messages) == 2 { // First user message + first AI response
-										// Save the entire conversation
-										conv := &database.Conversation{
-											ID:        a.currentConversationID,
-											Title:     a.statusBar.conversationTitle,
-											Provider:  a.config.CurrentProvider,
-											Model:     a.config.CurrentModel,
-											CreatedAt: time.Now(),
-											UpdatedAt: time.Now(),
-											Messages:  make([]database.Message, len(a.messages)),
-										}
-
-										// Convert UI messages to database messages
-										for i, msg := range a.messages {
-											role := "user"
-											if msg.Type == ProviderMessage {
-												role = "assistant"
-											}
-											conv.Messages[i] = database.Message{
-												ID:             uuid.New().String(),
-												ConversationID: conv.ID,
-												Role:           role,
-												Content:        msg.Content,
-												CreatedAt:      msg.Timestamp,
-											}
-										}
-
-										if err := a.db.SaveConversation(conv); err != nil {
-											fmt.Printf("Error saving conversation: %v\n", err)
-										}
-										a.refreshConversationList()
-									} else {
-										// Add just the new message for subsequent messages
-										dbMsg := &database.Message{
-											ID:             uuid.New().String(),
-											ConversationID: a.currentConversationID,
-											Role:           "assistant",
-											Content:        response,
-											CreatedAt:      time.Now(),
-										}
-										if err := a.db.AddMessage(dbMsg); err != nil {
-											fmt.Printf("Error adding message: %v\n", err)
-										}
-									}
-								}
+								// Save the exchange to the database
+								a.saveExchange(2, a.config.CurrentProvider, a.config.CurrentModel)
 
 								a.updateConversationView()
 							}()
@@ -559,6 +531,12 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 			if input != "" {
 				userInput := input
+
+				// An edited message replaces it and everything after it in a new branch
+				if a.editIndex >= 0 {
+					a.truncateMessages(a.editIndex)
+					a.editIndex = -1
+				}
 				
 				// Create and store user message
 				userMsg := NewMessage(a.nextMessageID, UserMessage, userInput, a.config, a.getNextCodeBlockNumber)
@@ -570,7 +548,7 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				a.input.Reset()
 
 				// If this is the first message, generate a title and create conversation in DB
-				if len(a.messages) == 1 {
+				if len(a.messages) == 1 && a.currentConversationID == "" {
 					go a.generateTitle(userInput)
 					a.currentConversationID = uuid.New().String()
 				}
@@ -584,127 +562,18 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 						a.statusBar.SetLoading(false)
 					}()
 
-					var response string
-					var err error
-
-					// Get provider instance based on current provider
-					providerName := strings.ToLower(a.config.CurrentProvider)
-					apiKey := a.config.APIKeys[providerName]
-
-					if apiKey == "" {
-						response = fmt.Sprintf("Error: Please provide an API key for %s in the settings", a.config.CurrentProvider)
-					} else {
-						// Build conversation history
-						var conversationHistory []string
-						for _, msg := range a.messages[:len(a.messages)-1] { // Exclude the message we just added
-							if msg.Type == UserMessage {
-								conversationHistory = append(conversationHistory, "User: "+msg.Content)
-							} else if msg.Type == ProviderMessage {
-								conversationHistory = append(conversationHistory, "Assistant: "+msg.Content)
-							}
-							// Skip SearchMessage type when building conversation history
-						}
-						
-						// Add current message
-						conversationHistory = append(conversationHistory, "User: "+userInput)
-						
-						// Join history with newlines
-						fullPrompt := strings.Join(conversationHistory, "\n")
-
-						switch providerName {
-						case "anthropic":
-							provider := anthropic.NewProvider(apiKey)
-							response, err = provider.SendMessage(context.Background(), fullPrompt, a.config.CurrentSystemPrompt, a.config.CurrentModel)
-						case "gemini":
-							provider := gemini.NewProvider(apiKey)
-							response, err = provider.SendMessage(context.Background(), fullPrompt, a.config.CurrentSystemPrompt, a.config.CurrentModel)
-						case "ollama":
-							provider := ollama.NewProvider(apiKey)
-							response, err = provider.SendMessage(context.Background(), fullPrompt, a.config.CurrentSystemPrompt, a.config.CurrentModel)
-						default:
-							cfg := providers.OpenAICompatibleConfig{
-								Name:   providerName,
-								APIKey: apiKey,
-							}
-							provider := providers.NewOpenAICompatibleProvider(cfg)
-							response, err = provider.SendMessage(context.Background(), fullPrompt, a.config.CurrentSystemPrompt, a.config.CurrentModel)
-						}
-
-						if err != nil {
-							response = "Error: " + err.Error()
-						}
-					}
+					response, provenance, _ := a.sendToProvider(userInput)
 
 					// Create and store provider message
 					providerMsg := NewMessage(a.nextMessageID, ProviderMessage, response, a.config, a.getNextCodeBlockNumber)
+					providerMsg.Provenance = provenance
 					a.messages = append(a.messages, providerMsg)
 					a.nextMessageID++
 
-					a.updateConversationView()
+					// Save the exchange to the database
+					a.saveExchange(2, a.config.CurrentProvider, a.config.CurrentModel)
 
-					// Save to database if we have a current conversation
-					if a.currentConversationID != "" {
-						if len(a.messages) == 2 { // First user message + first AI response
-							// Save the entire conversation
-							conv := &database.Conversation{
-								ID:        a.currentConversationID,
-								Title:     a.statusBar.conversationTitle,
-								Provider:  a.config.CurrentProvider,
-								Model:     a.config.CurrentModel,
-								CreatedAt: time.Now(),
-								UpdatedAt: time.Now(),
-								Messages:  make([]database.Message, 2), // Only save the current exchange
-							}
-
-							// Convert only the current exchange messages
-							for i := 0; i < 2; i++ {
-								msg := a.messages[i]
-								role := "user"
-								if msg.Type == ProviderMessage {
-									role = "assistant"
-								} else if msg.Type == SearchMessage {
-									role = "search"
-								}
-								conv.Messages[i] = database.Message{
-									ID:             uuid.New().String(),
-									ConversationID: conv.ID,
-									Role:           role,
-									Content:        msg.Content,
-									CreatedAt:      msg.Timestamp,
-								}
-							}
-
-							if err := a.db.SaveConversation(conv); err != nil {
-								fmt.Printf("Error saving conversation: %v\n", err)
-							}
-							a.refreshConversationList()
-						} else {
-							// Add just the last two messages (current exchange)
-							lastMsgIndex := len(a.messages) - 1
-							messages := []database.Message{
-								{
-									ID:             uuid.New().String(),
-									ConversationID: a.currentConversationID,
-									Role:           "user",
-									Content:        a.messages[lastMsgIndex-1].Content,
-									CreatedAt:      a.messages[lastMsgIndex-1].Timestamp,
-								},
-								{
-									ID:             uuid.New().String(),
-									ConversationID: a.currentConversationID,
-									Role:           "assistant",
-									Content:        a.messages[lastMsgIndex].Content,
-									CreatedAt:      a.messages[lastMsgIndex].Timestamp,
-								},
-							}
-							
-							for _, dbMsg := range messages {
-								if err := a.db.AddMessage(&dbMsg); err != nil {
-									fmt.Printf("Error adding message: %v\n", err)
-								}
-							}
-						}
-					}
+					a.updateConversationView()
 				}()
 				
 				return a, a.statusBar.spinner.Tick
@@ -716,7 +585,7 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		// Handle menu toggle
 		if msg.String() == "?" {
-			if a.input.Value() == "" {
+			if a.input.Value() == "" && !(a.currentView == "conversations" && a.conversationList.Searching()) && a.currentView != "import" && a.currentView != "exportdir" {
 				a.showMenu = !a.showMenu
 				return a, nil
 			}
@@ -734,15 +603,15 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				
 				switch selected.title {
 				case "New Conversation":
-					a.messages = make([]Message, 0)
-					a.nextMessageID = 1
-					a.currentConversationID = ""
-					a.statusBar.SetConversationTitle("New Conversation")
+					a.resetConversation()
 					a.updateConversationView()
 					a.refreshConversationList()
 				case "Conversations":
 					a.currentView = "conversations"
 					a.refreshConversationList()
+				case "Import":
+					a.currentView = "import"
+					return a, a.importView.Reset()
 				case "Settings":
 					a.currentView = "settings"
 				case "Help":
@@ -779,8 +648,12 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		a.usernameSettings.SetSize(msg.Width, msg.Height)
 		a.apiKeySettings.SetSize(msg.Width, msg.Height)
 		a.systemPromptSettings.SetSize(msg.Width, msg.Height)
+		a.searchPresetSettings.SetSize(msg.Width, msg.Height)
 		a.conversationList.SetSize(msg.Width, msg.Height)
 		a.helpView.SetSize(msg.Width, msg.Height)
+		a.linksView.SetSize(msg.Width, msg.Height)
+		a.importView.SetSize(msg.Width, msg.Height)
+		a.exportSettings.SetSize(msg.Width, msg.Height)
 
 		a.updateConversationView()
 		a.menu.SetSize(msg.Width, msg.Height)
@@ -790,10 +663,7 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			// Check if click is in status bar
 			if a.statusBar.inBounds(msg.X, msg.Y) {
 				// Start new conversation
-				a.messages = make([]Message, 0)
-				a.nextMessageID = 1
-				a.currentConversationID = ""
-				a.statusBar.SetConversationTitle("New Conversation")
+				a.resetConversation()
 				a.currentView = "chat"
 				a.showMenu = false
 				a.updateConversationView()
@@ -853,6 +723,10 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		var systemPromptCmd tea.Cmd
 		a.systemPromptSettings, systemPromptCmd = a.systemPromptSettings.Update(msg)
 		cmds = append(cmds, systemPromptCmd)
+	} else if a.currentView == "searchpresets" {
+		var presetCmd tea.Cmd
+		a.searchPresetSettings, presetCmd = a.searchPresetSettings.Update(msg)
+		cmds = append(cmds, presetCmd)
 	} else if a.currentView == "theme" {
 		var themeCmd tea.Cmd
 		a.settingsMenu.themeSettings, themeCmd = a.settingsMenu.themeSettings.Update(msg)
@@ -861,6 +735,18 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		var modelCmd tea.Cmd
 		a.settingsMenu.modelSettings, modelCmd = a.settingsMenu.modelSettings.Update(msg)
 		cmds = append(cmds, modelCmd)
+	} else if a.currentView == "links" {
+		var linksCmd tea.Cmd
+		a.linksView, linksCmd = a.linksView.Update(msg)
+		cmds = append(cmds, linksCmd)
+	} else if a.currentView == "import" {
+		var importCmd tea.Cmd
+		a.importView, importCmd = a.importView.Update(msg)
+		cmds = append(cmds, importCmd)
+	} else if a.currentView == "exportdir" {
+		var exportCmd tea.Cmd
+		a.exportSettings, exportCmd = a.exportSettings.Update(msg)
+		cmds = append(cmds, exportCmd)
 	} else if a.currentView == "help" {
 		var helpCmd tea.Cmd
 		a.helpView, helpCmd = a.helpView.Update(msg)
@@ -876,6 +762,19 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return a, tea.Batch(cmds...)
 }
 
+// resetConversation clears the chat so the next message starts a new conversation
+func (a *App) resetConversation() {
+	a.messages = make([]Message, 0)
+	a.nextMessageID = 1
+	a.currentConversationID = ""
+	a.totalCodeBlocks = 0 // Reset code block counter
+	a.historySummary = ""
+	a.summarizedTurns = 0
+	a.editIndex = -1
+	a.statusBar.SetConversationTitle("New Conversation")
+	a.statusBar.SetHistoryNotice("")
+}
+
 // updateConversationView updates the conversation window content
 func (a *App) updateConversationView() {
 	var content string
@@ -932,12 +831,20 @@ func (a *App) View() string {
 		return a.apiKeySettings.View()
 	case "systemprompts":
 		return a.systemPromptSettings.View()
+	case "searchpresets":
+		return a.searchPresetSettings.View()
 	case "theme":
 		return a.settingsMenu.themeSettings.View()
 	case "model":
 		return a.settingsMenu.modelSettings.View()
 	case "help":
 		return a.helpView.View()
+	case "links":
+		return a.linksView.View()
+	case "import":
+		return a.importView.View()
+	case "exportdir":
+		return a.exportSettings.View()
 	default:
 		return a.chatView()
 	}
@@ -985,7 +892,7 @@ func (a *App) generateTitle(userInput string) {
 			// Update conversation title in database if we have a current conversation
 			if a.currentConversationID != "" {
 				if err := a.db.UpdateConversationTitle(a.currentConversationID, title); err != nil {
-					fmt.Printf("Error updating conversation title: %v\n", err)
+					a.statusBar.SetError(fmt.Sprintf("Error updating conversation title: %v", err))
 				}
 			}
 		}
@@ -1000,7 +907,7 @@ func (a *App) openMessageInEditor(m Message) {
 // In the App struct, add a method to refresh the conversation list
 func (a *App) refreshConversationList() {
 	if a.conversationList != nil {
-		a.conversationList.loadConversations()
+		a.conversationList.refresh()
 	}
 }
 
@@ -1009,6 +916,82 @@ func (a *App) getNextCodeBlockNumber() int {
 	return a.totalCodeBlocks
 }
 
+// splitBackend extracts a leading @backend from a search query, e.g. "@searx golang" from /web@searx golang
+func splitBackend(query, defaultBackend string) (string, string) {
+	if !strings.HasPrefix(query, "@") {
+		return defaultBackend, query
+	}
+	name, rest, _ := strings.Cut(strings.TrimPrefix(query, "@"), " ")
+	return name, strings.TrimSpace(rest)
+}
+
+// searchOptions returns the default search options from the config
+func (a *App) searchOptions() search.Options {
+	settings := a.config.Settings.Search
+	return search.Options{
+		ExcludeDomains:    settings.ExcludeDomains,
+		SearchDepth:       settings.SearchDepth,
+		MaxResults:        settings.MaxResults,
+		Topic:             settings.Topic,
+		Days:              settings.Days,
+		TimeRange:         settings.TimeRange,
+		IncludeRawContent: settings.IncludeRawContent,
+		IncludeImages:     settings.IncludeImages,
+	}
+}
+
+// searchPresets returns the configured search presets by name
+func (a *App) searchPresets() map[string]search.Preset {
+	presets := make(map[string]search.Preset, len(a.config.SearchPresets))
+	for name, preset := range a.config.SearchPresets {
+		presets[strings.ToLower(name)] = search.Preset{
+			IncludeDomains: preset.Domains,
+			ExcludeDomains: preset.ExcludeDomains,
+			Topic:          preset.Topic,
+		}
+	}
+	return presets
+}
+
+// newLocationResolver returns the location resolver for the configured location settings
+func newLocationResolver(cfg *config.Config) *location.Resolver {
+	settings := cfg.Settings.Location
+	var cachePath string
+	if dir, err := config.Dir(); err == nil {
+		cachePath = filepath.Join(dir, "location.json")
+	}
+	return location.NewResolver(settings.Mode, location.IPInfo{
+		City:     settings.City,
+		Region:   settings.Region,
+		Country:  settings.Country,
+		Timezone: settings.Timezone,
+	}, cachePath)
+}
+
+// rawContentLimit is the number of characters of raw page content shown per /web result
+const rawContentLimit = 1500
+
+// truncateText shortens text to at most limit bytes, cutting at a word boundary
+func truncateText(text string, limit int) string {
+	text = strings.TrimSpace(text)
+	if len(text) <= limit {
+		return text
+	}
+	cut := strings.LastIndex(text[:limit], " ")
+	if cut <= 0 {
+		cut = limit
+	}
+	return strings.TrimSpace(text[:cut]) + "…"
+}
+
+// backendOrDefault returns the canonical search backend name, falling back to the default backend
+func backendOrDefault(name string) string {
+	if name == "" {
+		return search.DefaultBackend
+	}
+	return search.CanonicalName(name)
+}
+
 // Update the enhanceSearchQuery method to accept the enhancement type
 func (a *App) enhanceSearchQuery(query string, enhanceType search.EnhanceType) (string, error) {
 	return a.queryEnhancer.Enhance(query, enhanceType)
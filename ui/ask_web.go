@@ -0,0 +1,100 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/google/uuid"
+	"github.com/tedfulk/goatmeal/services/search"
+	"github.com/tedfulk/goatmeal/utils/prompts"
+)
+
+const (
+	// askWebPrefix marks the user message of a search-grounded question
+	askWebPrefix = "🔎 Ask web: "
+
+	// askWebResults is the number of search results given to the model
+	askWebResults = 5
+)
+
+// askWeb searches the web for query and has the current model answer from the results with citations
+func (a *App) askWeb(query string) tea.Cmd {
+	backendName, query := splitBackend(query, a.config.Settings.Search.Backend)
+	query, opts, err := search.ParseQuery(strings.TrimSpace(query), a.searchOptions(), a.searchPresets())
+	if err != nil {
+		a.statusBar.SetError(fmt.Sprintf("Invalid search: %v", err))
+		return nil
+	}
+	if query == "" {
+		a.input.Reset()
+		return nil
+	}
+
+	userMsg := NewMessage(a.nextMessageID, UserMessage, askWebPrefix+query, a.config, a.getNextCodeBlockNumber)
+	a.messages = append(a.messages, userMsg)
+	a.nextMessageID++
+
+	// If this is the first message, generate a title and create conversation
+	if len(a.messages) == 1 {
+		go a.generateTitle(query)
+		a.currentConversationID = uuid.New().String()
+	}
+
+	a.updateConversationView()
+	a.input.Reset()
+	a.statusBar.SetLoading(true)
+
+	go func() {
+		defer func() {
+			a.statusBar.SetLoading(false)
+		}()
+
+		var results []search.SearchResult
+		backend, err := search.NewBackend(backendName, search.BackendOptions{
+			APIKeys:  a.config.APIKeys,
+			SearxURL: a.config.Settings.Search.SearxURL,
+		})
+		if err == nil {
+			var searchResp *search.SearchResponse
+			if searchResp, err = backend.Search(query, opts); err == nil {
+				results = search.TopResults(searchResp.Results, askWebResults)
+			}
+		}
+
+		if err != nil || len(results) == 0 {
+			response := fmt.Sprintf("No search results found for: %s", query)
+			if err != nil {
+				response = fmt.Sprintf("Error performing search: %v", err)
+			}
+			errorMsg := NewMessage(a.nextMessageID, ProviderMessage, response, a.config, a.getNextCodeBlockNumber)
+			a.messages = append(a.messages, errorMsg)
+			a.nextMessageID++
+			a.saveExchange(2, a.config.CurrentProvider, a.config.CurrentModel)
+			a.updateConversationView()
+			return
+		}
+
+		// Ask the model before adding the sources so the grounding prompt replaces the question in history
+		grounding := prompts.GetGroundedAnswerPrompt(search.FormatGroundingSources(results), query)
+		response, provenance, ok := a.sendToProvider(grounding)
+		if ok {
+			response = search.LinkCitations(response, results)
+		}
+
+		sourcesMsg := NewMessage(a.nextMessageID, SearchMessage, search.FormatSources(results), a.config, a.getNextCodeBlockNumber)
+		sourcesMsg.Source = search.DisplayName(backendName)
+		a.messages = append(a.messages, sourcesMsg)
+		a.nextMessageID++
+
+		answerMsg := NewMessage(a.nextMessageID, ProviderMessage, response, a.config, a.getNextCodeBlockNumber)
+		answerMsg.Provenance = provenance
+		a.messages = append(a.messages, answerMsg)
+		a.nextMessageID++
+
+		a.saveExchange(3, a.config.CurrentProvider, a.config.CurrentModel)
+		a.updateConversationView()
+	}()
+
+	return a.statusBar.spinner.Tick
+}
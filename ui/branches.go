@@ -0,0 +1,259 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/tedfulk/goatmeal/database"
+	"github.com/tedfulk/goatmeal/services/search"
+)
+
+// messageIndex returns the position of the message with the given /c number, or -1
+func (a *App) messageIndex(arg string) int {
+	id, err := strconv.Atoi(strings.TrimSpace(arg))
+	if err != nil {
+		return -1
+	}
+	for i, msg := range a.messages {
+		if msg.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// startEdit puts an earlier user message in the input so it can be changed and sent as a new branch
+func (a *App) startEdit(arg string) {
+	i := a.messageIndex(arg)
+	if i < 0 {
+		a.statusBar.SetError("Usage: /edit N, where N is the number of one of your messages")
+		return
+	}
+	msg := a.messages[i]
+	if msg.Type != UserMessage || search.StripPrefix(msg.Content) != msg.Content {
+		a.statusBar.SetError("Only your chat messages can be edited")
+		return
+	}
+	if msg.DBID == "" || a.statusBar.isLoading {
+		a.statusBar.SetError("Wait for the reply before editing")
+		return
+	}
+
+	a.editIndex = i
+	a.input.Set(msg.Content)
+	a.statusBar.SetTemporaryText(fmt.Sprintf("✎ Editing message %d: enter sends it as a new branch, esc cancels", msg.ID))
+}
+
+// cancelEdit stops editing and clears the input
+func (a *App) cancelEdit() {
+	a.editIndex = -1
+	a.input.Reset()
+}
+
+// truncateMessages drops the messages from index i on, so the next message starts a new branch there
+func (a *App) truncateMessages(i int) {
+	a.messages = a.messages[:i]
+	a.nextMessageID = i + 1
+	a.totalCodeBlocks = 0
+	for _, msg := range a.messages {
+		a.totalCodeBlocks += len(msg.codeBlocks)
+	}
+	a.invalidateSummary(i)
+}
+
+// invalidateSummary drops the history summary when it covers turns from index i on
+// The saved summary is deleted too, so reopening the conversation doesn't bring it back
+func (a *App) invalidateSummary(i int) {
+	if i >= a.summarizedTurns {
+		return
+	}
+	a.historySummary = ""
+	a.summarizedTurns = 0
+	if a.currentConversationID != "" {
+		if err := a.db.DeleteConversationSummary(a.currentConversationID); err != nil {
+			a.statusBar.SetError(fmt.Sprintf("Failed to delete summary: %v", err))
+		}
+	}
+}
+
+// switchVariant shows another version of a message, with the latest replies that follow it
+// args is "N" for the next version of message N or "N K" for version K
+func (a *App) switchVariant(args string) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 || len(fields) > 2 {
+		a.statusBar.SetError("Usage: /vN for the next version of message N, or /vN K for version K")
+		return
+	}
+	i := a.messageIndex(fields[0])
+	if i < 0 || a.messages[i].DBID == "" {
+		a.statusBar.SetError(fmt.Sprintf("No saved message %s", fields[0]))
+		return
+	}
+	if a.statusBar.isLoading {
+		a.statusBar.SetError("Wait for the reply before switching versions")
+		return
+	}
+
+	conv, err := a.db.ExportConversation(a.currentConversationID, true)
+	if err != nil {
+		a.statusBar.SetError(fmt.Sprintf("Failed to load versions: %v", err))
+		return
+	}
+	siblings := database.Siblings(conv.Messages, a.messages[i].DBID)
+	if len(siblings) < 2 {
+		a.statusBar.SetError(fmt.Sprintf("Message %d has only one version", a.messages[i].ID))
+		return
+	}
+
+	next := a.messages[i].Variant % len(siblings) // Variant starts at 1, so this is the next index
+	if len(fields) == 2 {
+		k, err := strconv.Atoi(fields[1])
+		if err != nil || k < 1 || k > len(siblings) {
+			a.statusBar.SetError(fmt.Sprintf("Message %d has versions 1 to %d", a.messages[i].ID, len(siblings)))
+			return
+		}
+		next = k - 1
+	}
+
+	leafID := database.LatestLeaf(conv.Messages, siblings[next].ID)
+	if err := a.db.SetActiveLeaf(conv.ID, leafID); err != nil {
+		a.statusBar.SetError(fmt.Sprintf("Failed to switch version: %v", err))
+		return
+	}
+	a.loadBranch(conv, conv.Messages, leafID)
+	a.updateConversationView()
+}
+
+// regenerate answers the last message again and keeps the new reply as another version of the last one
+// args is empty for the current model, "provider/model" or a model of the current provider
+func (a *App) regenerate(args string) tea.Cmd {
+	n := len(a.messages)
+	if n < 2 || a.messages[n-1].Type != ProviderMessage || a.messages[n-2].Type != UserMessage {
+		a.statusBar.SetError("There is no reply to regenerate")
+		return nil
+	}
+	question := a.messages[n-2]
+	if search.StripPrefix(question.Content) != question.Content {
+		a.statusBar.SetError("Only replies to chat messages can be regenerated")
+		return nil
+	}
+	if a.messages[n-1].DBID == "" || a.statusBar.isLoading {
+		a.statusBar.SetError("Wait for the reply before regenerating it")
+		return nil
+	}
+
+	providerName, model := a.regenModel(strings.TrimSpace(args))
+
+	// Drop the reply so the question is the last message, as when it was first sent
+	a.truncateMessages(n - 1)
+	a.updateConversationView()
+	a.statusBar.SetLoading(true)
+
+	go func() {
+		defer func() {
+			a.statusBar.SetLoading(false)
+		}()
+
+		response, provenance, _ := a.sendToModel(question.Content, providerName, model)
+		replyMsg := NewMessage(a.nextMessageID, ProviderMessage, response, a.config, a.getNextCodeBlockNumber)
+		replyMsg.Provenance = provenance
+		a.messages = append(a.messages, replyMsg)
+		a.nextMessageID++
+
+		// Saved with the question as its parent, next to the earlier replies
+		a.saveExchange(1, providerName, model)
+		a.updateConversationView()
+	}()
+
+	return a.statusBar.spinner.Tick
+}
+
+// regenModel returns the provider and model named in a /regen argument
+// Model names can contain slashes, so the part before the first one is only a provider if it names one
+func (a *App) regenModel(arg string) (string, string) {
+	if arg == "" {
+		return a.config.CurrentProvider, a.config.CurrentModel
+	}
+	if providerName, model, ok := strings.Cut(arg, "/"); ok && model != "" {
+		if _, known := a.config.APIKeys[strings.ToLower(providerName)]; known {
+			return strings.ToLower(providerName), model
+		}
+	}
+	return a.config.CurrentProvider, arg
+}
+
+// loadBranch replaces the chat messages with the branch of tree ending in leafID
+func (a *App) loadBranch(conv *database.Conversation, tree []database.Message, leafID string) {
+	branch := database.ActiveBranch(tree, leafID)
+
+	// Keep the summary if it only covers turns both branches share
+	shared := 0
+	for shared < len(branch) && shared < len(a.messages) && a.messages[shared].DBID == branch[shared].ID {
+		shared++
+	}
+	a.invalidateSummary(shared)
+
+	a.messages = make([]Message, 0, len(branch))
+	a.nextMessageID = 1
+	a.totalCodeBlocks = 0
+	a.editIndex = -1
+
+	isSearch := search.IsBackend(conv.Provider)
+	for _, dbMsg := range branch {
+		msgType := UserMessage
+		switch dbMsg.Role {
+		case "assistant":
+			msgType = ProviderMessage
+		case "search":
+			msgType = SearchMessage
+		}
+
+		// NewMessage numbers the code blocks in order, the same as when they were first shown
+		msg := NewMessage(a.nextMessageID, msgType, dbMsg.Content, a.config, a.getNextCodeBlockNumber)
+		msg.Timestamp = dbMsg.CreatedAt
+		msg.Provenance = dbMsg.Provenance
+		msg.DBID = dbMsg.ID
+		if msgType == ProviderMessage && msg.Provenance.Model == "" && !isSearch {
+			// Messages saved before provenance was tracked use the conversation's model
+			msg.Provenance.Provider = conv.Provider
+			msg.Provenance.Model = conv.Model
+		}
+		if msgType == SearchMessage && isSearch {
+			msg.Source = search.DisplayName(conv.Provider)
+		}
+		a.messages = append(a.messages, msg)
+		a.nextMessageID++
+	}
+	a.setVariants(tree)
+}
+
+// refreshVariants updates the version pagers after messages were added to the conversation
+func (a *App) refreshVariants() {
+	if a.currentConversationID == "" {
+		return
+	}
+	tree, err := a.db.GetConversationMessages(a.currentConversationID)
+	if err != nil {
+		a.statusBar.SetError(fmt.Sprintf("Failed to load versions: %v", err))
+		return
+	}
+	a.setVariants(tree)
+}
+
+// setVariants sets the position of each chat message among its versions in tree
+func (a *App) setVariants(tree []database.Message) {
+	for i := range a.messages {
+		if a.messages[i].DBID == "" {
+			continue
+		}
+		siblings := database.Siblings(tree, a.messages[i].DBID)
+		a.messages[i].Variants = len(siblings)
+		for k, sibling := range siblings {
+			if sibling.ID == a.messages[i].DBID {
+				a.messages[i].Variant = k + 1
+			}
+		}
+	}
+}
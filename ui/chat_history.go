@@ -0,0 +1,248 @@
+package ui
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tedfulk/goatmeal/database"
+	"github.com/tedfulk/goatmeal/services/history"
+	"github.com/tedfulk/goatmeal/services/providers"
+	"github.com/tedfulk/goatmeal/services/providers/anthropic"
+	"github.com/tedfulk/goatmeal/services/providers/gemini"
+	"github.com/tedfulk/goatmeal/services/providers/ollama"
+)
+
+const (
+	defaultSummaryProvider = "groq"
+	defaultSummaryModel    = "llama-3.1-8b-instant"
+)
+
+// newChatProvider returns the chat provider for the given provider name
+func newChatProvider(providerName, apiKey string) providers.Provider {
+	switch providerName {
+	case "anthropic":
+		return anthropic.NewProvider(apiKey)
+	case "gemini":
+		return gemini.NewProvider(apiKey)
+	case "ollama":
+		return ollama.NewProvider(apiKey)
+	default:
+		return providers.NewOpenAICompatibleProvider(providers.OpenAICompatibleConfig{
+			Name:   providerName,
+			APIKey: apiKey,
+		})
+	}
+}
+
+// sendToProvider sends message to the current provider together with the conversation history
+// Errors are returned as the response text so they show up in the chat, with ok set to false.
+// The provenance describes the model, system prompt and usage of the response
+func (a *App) sendToProvider(message string) (response string, provenance database.Provenance, ok bool) {
+	return a.sendToModel(message, a.config.CurrentProvider, a.config.CurrentModel)
+}
+
+// sendToModel is sendToProvider for a provider and model other than the current ones
+func (a *App) sendToModel(message, providerName, model string) (string, database.Provenance, bool) {
+	provenance := a.currentProvenance()
+	provenance.Provider = providerName
+	provenance.Model = model
+	displayName := providerName
+	providerName = strings.ToLower(providerName)
+	apiKey := a.config.APIKeys[providerName]
+	if apiKey == "" {
+		return fmt.Sprintf("Error: Please provide an API key for %s in the settings", displayName), provenance, false
+	}
+
+	fullPrompt := a.buildHistoryPrompt(message, model)
+
+	provider := newChatProvider(providerName, apiKey)
+	start := time.Now()
+	var response string
+	var err error
+	if detailed, ok := provider.(providers.DetailedProvider); ok {
+		var resp providers.Response
+		resp, err = detailed.SendMessageDetailed(context.Background(), fullPrompt, a.config.CurrentSystemPrompt, model)
+		response = resp.Content
+		provenance.InputTokens = resp.InputTokens
+		provenance.OutputTokens = resp.OutputTokens
+		if params, jsonErr := json.Marshal(resp.Params); jsonErr == nil && len(resp.Params) > 0 {
+			provenance.Params = string(params)
+		}
+	} else {
+		response, err = provider.SendMessage(context.Background(), fullPrompt, a.config.CurrentSystemPrompt, model)
+	}
+	provenance.Latency = time.Since(start)
+	if err != nil {
+		return "Error: " + err.Error(), provenance, false
+	}
+	return response, provenance, true
+}
+
+// currentProvenance describes the current provider, model and system prompt
+func (a *App) currentProvenance() database.Provenance {
+	provenance := database.Provenance{
+		Provider: a.config.CurrentProvider,
+		Model:    a.config.CurrentModel,
+	}
+	if prompt := a.config.CurrentSystemPrompt; prompt != "" {
+		sum := sha256.Sum256([]byte(prompt))
+		provenance.SystemPromptHash = hex.EncodeToString(sum[:])[:12]
+		for _, saved := range a.config.SystemPrompts {
+			if saved.Content == prompt {
+				provenance.SystemPrompt = saved.Title
+				break
+			}
+		}
+	}
+	return provenance
+}
+
+// historyTurns converts the chat messages before the one just added into history turns
+// Search results are included so follow-up questions can refer to them
+func (a *App) historyTurns() []history.Turn {
+	var turns []history.Turn
+	for _, msg := range a.messages[:len(a.messages)-1] { // Exclude the message we just added
+		turns = append(turns, history.Turn{Role: dbRole(msg), Content: msg.Content})
+	}
+	return turns
+}
+
+// dbRole returns the role a chat message is stored with
+func dbRole(msg Message) string {
+	switch msg.Type {
+	case ProviderMessage:
+		return "assistant"
+	case SearchMessage:
+		return "search"
+	default:
+		return "user"
+	}
+}
+
+// saveExchange stores the last n messages, creating the conversation if they are its first messages
+func (a *App) saveExchange(n int, provider, model string) {
+	if a.currentConversationID == "" || n > len(a.messages) {
+		return
+	}
+
+	start := len(a.messages) - n
+	dbMessages := make([]database.Message, n)
+	for i := range dbMessages {
+		// Each message follows the one before it in the chat, which is the active branch
+		msg := &a.messages[start+i]
+		if msg.DBID == "" {
+			msg.DBID = uuid.New().String()
+		}
+		var parentID string
+		if start+i > 0 {
+			parentID = a.messages[start+i-1].DBID
+		}
+		dbMessages[i] = database.Message{
+			ID:             msg.DBID,
+			ConversationID: a.currentConversationID,
+			Role:           dbRole(*msg),
+			Content:        msg.Content,
+			CreatedAt:      msg.Timestamp,
+			ParentID:       parentID,
+			Provenance:     msg.Provenance,
+		}
+	}
+
+	if len(a.messages) == n {
+		conv := &database.Conversation{
+			ID:        a.currentConversationID,
+			Title:     a.statusBar.conversationTitle,
+			Provider:  provider,
+			Model:     model,
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+			Messages:  dbMessages,
+		}
+		if err := a.db.SaveConversation(conv); err != nil {
+			a.statusBar.SetError(fmt.Sprintf("Error saving conversation: %v", err))
+			return
+		}
+		a.applyAutoTags(provider)
+		a.refreshConversationList()
+		return
+	}
+
+	for i := range dbMessages {
+		if err := a.db.AddMessage(&dbMessages[i]); err != nil {
+			a.statusBar.SetError(fmt.Sprintf("Error adding message: %v", err))
+		}
+	}
+	a.refreshVariants()
+}
+
+// buildHistoryPrompt fits the conversation history and the new message into the model's context window
+func (a *App) buildHistoryPrompt(message, model string) string {
+	turns := append(a.historyTurns(), history.Turn{Role: "user", Content: message})
+	settings := a.config.Settings.History
+
+	budgeter := history.NewBudgeter(model, settings.ContextWindow, settings.ReserveTokens, a.historySummarizer())
+	result, err := budgeter.Fit(context.Background(), a.config.CurrentSystemPrompt, turns, a.historySummary, a.summarizedTurns)
+	if err != nil {
+		// Fall back to dropping older turns if the summary could not be generated
+		a.statusBar.SetError(fmt.Sprintf("Failed to summarize history: %v", err))
+		budgeter = history.NewBudgeter(model, settings.ContextWindow, settings.ReserveTokens, nil)
+		result, _ = budgeter.Fit(context.Background(), a.config.CurrentSystemPrompt, turns, "", 0)
+	}
+
+	if result.Summarized {
+		a.historySummary = result.Summary
+		a.summarizedTurns = result.SummarizedTurns
+		if a.currentConversationID != "" {
+			summary := &database.ConversationSummary{
+				ConversationID:  a.currentConversationID,
+				Summary:         result.Summary,
+				SummarizedTurns: result.SummarizedTurns,
+				UpdatedAt:       time.Now(),
+			}
+			if err := a.db.SaveConversationSummary(summary); err != nil {
+				a.statusBar.SetError(fmt.Sprintf("Failed to save summary: %v", err))
+			}
+		}
+	}
+
+	switch {
+	case !result.Truncated():
+		a.statusBar.SetHistoryNotice("")
+	case result.Summary != "" && result.SummarizedTurns == result.OmittedTurns:
+		a.statusBar.SetHistoryNotice(fmt.Sprintf("✂ %d earlier turns summarized", result.OmittedTurns))
+	default:
+		a.statusBar.SetHistoryNotice(fmt.Sprintf("✂ %d earlier turns dropped", result.OmittedTurns))
+	}
+
+	return result.Prompt
+}
+
+// historySummarizer returns the summarizer configured for long conversations, or nil if summaries are off
+func (a *App) historySummarizer() history.Summarizer {
+	settings := a.config.Settings.History
+	if !settings.Summarize {
+		return nil
+	}
+
+	providerName := settings.SummaryProvider
+	if providerName == "" {
+		providerName = defaultSummaryProvider
+	}
+	model := settings.SummaryModel
+	if model == "" {
+		model = defaultSummaryModel
+	}
+
+	apiKey := a.config.APIKeys[providerName]
+	if apiKey == "" {
+		return nil
+	}
+
+	return history.NewSummarizer(newChatProvider(providerName, apiKey), model)
+}
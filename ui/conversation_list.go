@@ -1,22 +1,24 @@
 package ui
 
 import (
-	"encoding/json"
 	"fmt"
-	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 	"time"
-	"unicode"
 
 	"github.com/atotto/clipboard"
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/glamour"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/tedfulk/goatmeal/config"
 	"github.com/tedfulk/goatmeal/database"
+	"github.com/tedfulk/goatmeal/services/export"
+	"github.com/tedfulk/goatmeal/services/search"
 	"github.com/tedfulk/goatmeal/ui/theme"
 	"github.com/tedfulk/goatmeal/utils/models"
 )
@@ -28,18 +30,100 @@ type ConversationItem struct {
 	title     string
 	provider  string
 	model     string
-	messages  []database.Message
+	messageCount int
+	snippet   string // Matching excerpt when the item is a search result
+	matchID   string // Message to jump to when the item is a search result
+	tags      []string
+	pinned    bool
+	marked    bool      // Marked to be exported with other conversations
+	deletedAt time.Time // When the item was moved to the trash, zero outside the trash
 }
 
-func (i ConversationItem) Title() string       { return i.title }
-func (i ConversationItem) Description() string { return "" }
+func (i ConversationItem) Title() string {
+	title := highlightMatches(i.title)
+	if i.pinned {
+		title = "📌 " + title
+	}
+	if i.marked {
+		title = "✓ " + title
+	}
+	return title
+}
+func (i ConversationItem) Description() string {
+	if i.snippet != "" {
+		return highlightMatches(i.snippet)
+	}
+	if !i.deletedAt.IsZero() {
+		return strings.TrimSpace("Deleted " + i.deletedAt.Format("Jan 2") + " " + formatTags(i.tags))
+	}
+	return strings.TrimSpace(messageCount(i.messageCount) + " " + formatTags(i.tags))
+}
+
+// messageCount describes the number of messages in a conversation
+func messageCount(n int) string {
+	if n == 1 {
+		return "1 message"
+	}
+	return fmt.Sprintf("%d messages", n)
+}
 func (i ConversationItem) FilterValue() string { return i.title }
 
+// searchResultLimit is the maximum number of conversations shown for a search
+const searchResultLimit = 100
+
+// conversationPageSize is how many conversations are loaded at a time
+// More are loaded as the selection gets close to the end of the list
+const conversationPageSize = 100
+
+// previewCacheSize is the most rendered messages kept, the cache starts over when it's full
+const previewCacheSize = 1000
+
+// undoTimeout is how long a deleted conversation can be restored with the Undo key
+const undoTimeout = 5 * time.Second
+
+// highlightMatches styles the terms marked by a full-text search
+func highlightMatches(text string) string {
+	if !strings.Contains(text, database.HighlightStart) {
+		return text
+	}
+	matchStyle := lipgloss.NewStyle().
+		Foreground(theme.CurrentTheme.Primary.GetColor()).
+		Bold(true)
+
+	var sb strings.Builder
+	for {
+		start := strings.Index(text, database.HighlightStart)
+		if start < 0 {
+			break
+		}
+		end := strings.Index(text[start:], database.HighlightEnd)
+		if end < 0 {
+			break
+		}
+		end += start
+		sb.WriteString(text[:start])
+		sb.WriteString(matchStyle.Render(text[start+len(database.HighlightStart) : end]))
+		text = text[end+len(database.HighlightEnd):]
+	}
+	sb.WriteString(text)
+	return strings.NewReplacer(database.HighlightStart, "", database.HighlightEnd, "").Replace(sb.String())
+}
+
 type KeyMap struct {
 	Back key.Binding
 	Delete key.Binding
 	SwitchFocus key.Binding
 	Export key.Binding
+	ExportTree key.Binding
+	Search key.Binding
+	Open key.Binding
+	OpenWithModel key.Binding
+	EditTags key.Binding
+	FilterTag key.Binding
+	Pin key.Binding
+	Undo key.Binding
+	Trash key.Binding
+	Mark key.Binding
 }
 
 var DefaultKeyMap = KeyMap{
@@ -49,7 +133,7 @@ var DefaultKeyMap = KeyMap{
 	),
 	Delete: key.NewBinding(
 		key.WithKeys("ctrl+d"),
-		key.WithHelp("ctrl+d", "delete conversation"),
+		key.WithHelp("ctrl+d", "move to trash"),
 	),
 	SwitchFocus: key.NewBinding(
 		key.WithKeys("tab"),
@@ -57,7 +141,47 @@ var DefaultKeyMap = KeyMap{
 	),
 	Export: key.NewBinding(
 		key.WithKeys("ctrl+e"),
-		key.WithHelp("ctrl+e", "export conversation"),
+		key.WithHelp("ctrl+e", "export as..."),
+	),
+	ExportTree: key.NewBinding(
+		key.WithKeys("E"),
+		key.WithHelp("E", "export all branches as JSON"),
+	),
+	Search: key.NewBinding(
+		key.WithKeys("/", "ctrl+f"),
+		key.WithHelp("/", "search"),
+	),
+	Open: key.NewBinding(
+		key.WithKeys("enter"),
+		key.WithHelp("enter", "continue conversation"),
+	),
+	OpenWithModel: key.NewBinding(
+		key.WithKeys("ctrl+o"),
+		key.WithHelp("ctrl+o", "continue with its model"),
+	),
+	EditTags: key.NewBinding(
+		key.WithKeys("t"),
+		key.WithHelp("t", "edit tags"),
+	),
+	FilterTag: key.NewBinding(
+		key.WithKeys("#"),
+		key.WithHelp("#", "filter by tag"),
+	),
+	Pin: key.NewBinding(
+		key.WithKeys("p"),
+		key.WithHelp("p", "pin/unpin"),
+	),
+	Undo: key.NewBinding(
+		key.WithKeys("u"),
+		key.WithHelp("u", "undo delete"),
+	),
+	Trash: key.NewBinding(
+		key.WithKeys("T"),
+		key.WithHelp("T", "trash"),
+	),
+	Mark: key.NewBinding(
+		key.WithKeys(" "),
+		key.WithHelp("space", "mark for export"),
 	),
 }
 
@@ -76,10 +200,27 @@ type ConversationListView struct {
 	keys     KeyMap
 	viewport viewport.Model
 	focused  string
+	search   textinput.Model
+	query    string // Search the list currently shows results for, empty for all conversations
+	tagInput textinput.Model
+	tagging  string // Conversation whose tags are being edited
+	trash    bool   // The list shows the conversations in the trash
+	undoID   string // Conversation the Undo key restores, set right after a delete
+	purgeID  string // Conversation ctrl+d permanently deletes when pressed again
+	marked   map[string]bool // Conversations marked to be exported together
+	exportFormat int          // Index in export.Formats of the format picked last
+	hasMore  bool             // Not every conversation is loaded into the list yet
+	cursor   database.Conversation // Last conversation loaded, the next page starts after it
+	previews map[string]string // Messages rendered with glamour, by message ID
 }
 
 type ResetTitleMsg struct{}
 
+// undoExpiredMsg ends the chance to undo the delete of a conversation
+type undoExpiredMsg struct {
+	id string
+}
+
 func NewConversationListView(db *database.DB, cfg *config.Config) *ConversationListView {
 	l := list.New([]list.Item{}, list.NewDefaultDelegate(), 0, 0)
 	l.Title = "Conversations"
@@ -96,6 +237,15 @@ func NewConversationListView(db *database.DB, cfg *config.Config) *ConversationL
 			DefaultKeyMap.Delete,
 			DefaultKeyMap.SwitchFocus,
 			DefaultKeyMap.Export,
+			DefaultKeyMap.ExportTree,
+			DefaultKeyMap.Search,
+			DefaultKeyMap.Open,
+			DefaultKeyMap.OpenWithModel,
+			DefaultKeyMap.EditTags,
+			DefaultKeyMap.FilterTag,
+			DefaultKeyMap.Pin,
+			DefaultKeyMap.Trash,
+			DefaultKeyMap.Mark,
 		}
 	}
 	l.AdditionalFullHelpKeys = l.AdditionalShortHelpKeys
@@ -107,6 +257,16 @@ func NewConversationListView(db *database.DB, cfg *config.Config) *ConversationL
 		Padding(1, 1)
 	vp.MouseWheelEnabled = true
 
+	search := textinput.New()
+	search.Placeholder = "Search messages or #tag..."
+	search.Prompt = "🔍 "
+	search.Width = 26
+
+	tagInput := textinput.New()
+	tagInput.Placeholder = "tags, separated by spaces"
+	tagInput.Prompt = "🏷 "
+	tagInput.Width = 26
+
 	view := &ConversationListView{
 		db:       db,
 		config:   cfg,
@@ -114,6 +274,10 @@ func NewConversationListView(db *database.DB, cfg *config.Config) *ConversationL
 		keys:     DefaultKeyMap,
 		viewport: vp,
 		focused:  "list",
+		search:   search,
+		tagInput: tagInput,
+		marked:   make(map[string]bool),
+		previews: make(map[string]string),
 	}
 
 	// Load all conversations
@@ -122,60 +286,266 @@ func NewConversationListView(db *database.DB, cfg *config.Config) *ConversationL
 	// Load messages for the first conversation if any exist
 	if len(view.list.Items()) > 0 {
 		firstConv := view.list.Items()[0].(ConversationItem)
-		view.loadMessages(firstConv.id)
+		view.loadMessages(firstConv.id, "")
 	}
 
 	return view
 }
 
-func (c *ConversationListView) loadConversations() {
-	// Load all conversations at once
-	conversations, err := c.db.GetConversations(0, -1)
+// refresh reloads the list, keeping the current search if there is one
+func (c *ConversationListView) refresh() {
+	if c.query != "" {
+		c.runSearch(c.query)
+		return
+	}
+	c.loadConversations()
+}
+
+// runSearch replaces the list with the conversations matching query
+// Words starting with # only keep conversations with that tag, the rest is a full-text search
+func (c *ConversationListView) runSearch(query string) {
+	c.query = strings.TrimSpace(query)
+	if c.query == "" {
+		c.loadConversations()
+		return
+	}
+
+	items, err := c.searchItems(c.query)
 	if err != nil {
+		c.list.SetItems(nil)
+		c.messages = nil
+		c.viewport.SetContent(fmt.Sprintf("Search failed: %v", err))
 		return
 	}
+	c.setItems(items)
+	c.list.Select(0)
+	c.list.Title = c.title()
 
+	if len(items) > 0 {
+		first := items[0].(ConversationItem)
+		c.loadMessages(first.id, first.matchID)
+	} else {
+		c.messages = nil
+		c.viewport.SetContent("No conversations match your search" + c.tagHint())
+	}
+}
+
+// tagHint lists the tags in use when the search filters by tag
+func (c *ConversationListView) tagHint() string {
+	if !strings.Contains(c.query, "#") {
+		return ""
+	}
+	tags, err := c.db.GetTags()
+	if err != nil || len(tags) == 0 {
+		return "\n\nNo conversations are tagged yet, press t to tag one"
+	}
+	hint := "\n\nTags:"
+	for _, tag := range tags {
+		hint += fmt.Sprintf("\n  #%s (%d)", tag.Name, tag.Conversations)
+	}
+	return hint
+}
+
+// searchItems returns the list items for a search, see runSearch
+func (c *ConversationListView) searchItems(query string) ([]list.Item, error) {
+	var tags, words []string
+	for _, word := range strings.Fields(query) {
+		if strings.HasPrefix(word, "#") {
+			if tag := database.NormalizeTag(word); tag != "" {
+				tags = append(tags, tag)
+			}
+			continue
+		}
+		words = append(words, word)
+	}
+
+	if len(words) == 0 {
+		conversations, err := c.db.GetTaggedConversations(tags)
+		if err != nil {
+			return nil, err
+		}
+		var items []list.Item
+		for _, conv := range conversations {
+			items = append(items, conversationItem(conv))
+		}
+		return items, nil
+	}
+
+	// Conversations with every tag, by ID
+	var tagged map[string]bool
+	if len(tags) > 0 {
+		conversations, err := c.db.GetTaggedConversations(tags)
+		if err != nil {
+			return nil, err
+		}
+		tagged = make(map[string]bool)
+		for _, conv := range conversations {
+			tagged[conv.ID] = true
+		}
+	}
+
+	hits, err := c.db.SearchConversations(strings.Join(words, " "), searchResultLimit)
+	if err != nil {
+		return nil, err
+	}
 	var items []list.Item
-	for _, conv := range conversations {
+	for _, hit := range hits {
+		if tagged != nil && !tagged[hit.ConversationID] {
+			continue
+		}
 		items = append(items, ConversationItem{
-			id:       conv.ID,
-			title:    conv.Title,
-			provider: conv.Provider,
-			model:    conv.Model,
+			id:      hit.ConversationID,
+			title:   hit.Title,
+			snippet: strings.Join(strings.Fields(hit.Snippet), " "),
+			matchID: hit.MessageID,
 		})
 	}
-	c.list.SetItems(items)
+	return items, nil
+}
+
+// conversationItem returns the list item of a conversation
+func conversationItem(conv database.Conversation) ConversationItem {
+	return ConversationItem{
+		id:       conv.ID,
+		title:    conv.Title,
+		provider: conv.Provider,
+		model:    conv.Model,
+		messageCount: conv.MessageCount,
+		tags:      conv.Tags,
+		pinned:    conv.Pinned,
+		deletedAt: conv.DeletedAt,
+	}
+}
+
+// clearSearch returns the list to all conversations
+func (c *ConversationListView) clearSearch() {
+	c.search.SetValue("")
+	c.search.Blur()
+	c.query = ""
+	c.focused = "list"
+	c.list.Title = c.title()
+	c.loadConversations()
+}
+
+func (c *ConversationListView) title() string {
+	if len(c.marked) > 0 {
+		return fmt.Sprintf("%d Marked", len(c.marked))
+	}
+	if c.trash {
+		return fmt.Sprintf("Trash (%d)", len(c.list.Items()))
+	}
+	if c.query != "" {
+		return fmt.Sprintf("%d Matches", len(c.list.Items()))
+	}
+	return "Conversations"
+}
+
+// loadConversations fills the list with the first page of conversations, or with as many as
+// were loaded before so a refresh doesn't lose the selection
+func (c *ConversationListView) loadConversations() {
+	var conversations []database.Conversation
+	var err error
+	if c.trash {
+		conversations, err = c.db.GetDeletedConversations()
+		c.hasMore = false
+	} else {
+		limit := conversationPageSize
+		for limit < len(c.list.Items()) {
+			limit += conversationPageSize
+		}
+		conversations, err = c.db.GetConversations(0, limit)
+		c.hasMore = len(conversations) == limit
+		if len(conversations) > 0 {
+			c.cursor = conversations[len(conversations)-1]
+		}
+	}
+	if err != nil {
+		c.list.SetItems(nil)
+		c.messages = nil
+		c.viewport.SetContent(fmt.Sprintf("Failed to load conversations: %v", err))
+		return
+	}
+
+	var items []list.Item
+	for _, conv := range conversations {
+		items = append(items, conversationItem(conv))
+	}
+	c.setItems(items)
+	c.list.Title = c.title()
 
 	// If there are conversations, load messages for the first one
 	if len(items) > 0 {
 		firstConv := items[0].(ConversationItem)
-		c.loadMessages(firstConv.id)
+		c.loadMessages(firstConv.id, "")
+	} else {
+		c.messages = nil
+		if c.trash {
+			c.viewport.SetContent("The trash is empty")
+		} else {
+			c.viewport.SetContent("Select a conversation to view messages")
+		}
 	}
 }
 
-func (c *ConversationListView) loadMessages(conversationID string) {
-	messages, err := c.db.GetConversationMessages(conversationID)
+// loadMore adds the next page of conversations to the list
+func (c *ConversationListView) loadMore() {
+	items := c.list.Items()
+	conversations, err := c.db.GetConversationsAfter(c.cursor, conversationPageSize)
 	if err != nil {
+		c.viewport.SetContent(fmt.Sprintf("Failed to load conversations: %v", err))
 		return
 	}
-	c.messages = messages
+	c.hasMore = len(conversations) == conversationPageSize
+	if len(conversations) > 0 {
+		c.cursor = conversations[len(conversations)-1]
+	}
 
-	// Get the conversation details to access the model name
-	conversations, err := c.db.GetConversations(0, -1)
-	if err != nil {
-		return
+	// The page starts after the last one loaded, but a conversation listed in the search results
+	// or restored from the trash since may already be in the list
+	listed := make(map[string]bool, len(items))
+	for _, item := range items {
+		listed[item.(ConversationItem).id] = true
 	}
-	
-	var currentConv *database.Conversation
+	more := make([]list.Item, len(items), len(items)+len(conversations))
+	copy(more, items)
 	for _, conv := range conversations {
-		if conv.ID == conversationID {
-			currentConv = &conv
-			break
+		if !listed[conv.ID] {
+			more = append(more, conversationItem(conv))
 		}
 	}
+	c.setItems(more)
+}
+
+// renderPreview renders a reply with glamour, reusing the rendering from an earlier selection
+func (c *ConversationListView) renderPreview(msg database.Message) string {
+	if rendered, ok := c.previews[msg.ID]; ok {
+		return rendered
+	}
+	rendered, err := glamour.Render(msg.Content, "dark")
+	if err != nil {
+		return msg.Content
+	}
+	if len(c.previews) >= previewCacheSize {
+		c.previews = make(map[string]string)
+	}
+	c.previews[msg.ID] = rendered
+	return rendered
+}
+
+// loadMessages shows a conversation, scrolled to matchID when it's set
+func (c *ConversationListView) loadMessages(conversationID, matchID string) {
+	// The conversation details give the model name, and work for conversations in the trash
+	currentConv, err := c.db.ExportConversation(conversationID, false)
+	if err != nil {
+		c.messages = nil
+		c.viewport.SetContent(fmt.Sprintf("Failed to load conversation: %v", err))
+		return
+	}
+	c.messages = currentConv.Messages
 
 	// Update viewport content
 	var content string
+	matchLine := -1
 	if len(c.messages) > 0 {
 		for _, msg := range c.messages {
 			var prefix string
@@ -185,12 +555,14 @@ func (c *ConversationListView) loadMessages(conversationID string) {
 				prefix = c.config.Settings.Username
 			} else if msg.Role == "search" {
 				prefixColor = theme.CurrentTheme.Message.AIText.GetColor()
-				prefix = "Tavily"
+				prefix = search.DisplayName(currentConv.Provider)
 			} else {
 				// Color model name with AIText color
 				prefixColor = theme.CurrentTheme.Message.AIText.GetColor()
-				if currentConv.Provider == "tavily" {
-					prefix = "Tavily"
+				if msg.Model != "" {
+					prefix = models.StripModelsPrefix(msg.Model)
+				} else if search.IsBackend(currentConv.Provider) {
+					prefix = search.DisplayName(currentConv.Provider)
 				} else {
 					prefix = models.StripModelsPrefix(currentConv.Model)
 				}
@@ -205,13 +577,22 @@ func (c *ConversationListView) loadMessages(conversationID string) {
 				Render(" 📋")
 
 			prefixWithButton := prefixStyle.Render(prefix) + copyButton
+			if details := provenanceDetails(msg.Provenance); details != "" {
+				prefixWithButton += lipgloss.NewStyle().
+					Foreground(theme.CurrentTheme.Message.Timestamp.GetColor()).
+					Render("  " + details)
+			}
+			if msg.ID == matchID {
+				matchLine = strings.Count(content, "\n")
+				prefixWithButton += lipgloss.NewStyle().
+					Foreground(theme.CurrentTheme.Secondary.GetColor()).
+					Render("  ◀ match")
+			}
 
 			// Render message content with Glamour if enabled
 			msgContent := msg.Content
 			if c.config.Settings.OutputGlamour && (msg.Role == "assistant" || msg.Role == "search") {
-				if rendered, err := glamour.Render(msg.Content, "dark"); err == nil {
-					msgContent = rendered
-				}
+				msgContent = c.renderPreview(msg)
 			}
 
 			content += prefixWithButton + "\n" + msgContent + "\n\n"
@@ -220,94 +601,208 @@ func (c *ConversationListView) loadMessages(conversationID string) {
 		content = "Select a conversation to view messages"
 	}
 	c.viewport.SetContent(content)
+	if matchLine >= 0 {
+		c.viewport.SetYOffset(matchLine)
+	} else {
+		c.viewport.GotoTop()
+	}
 }
 
-func (c *ConversationListView) exportConversation(id string) (tea.Cmd, error) {
-	// Set the title immediately
+// provenanceDetails summarizes the provider, system prompt, latency and token usage of a message
+func provenanceDetails(p database.Provenance) string {
+	var parts []string
+	if p.Provider != "" {
+		parts = append(parts, p.Provider)
+	}
+	if p.SystemPrompt != "" {
+		parts = append(parts, "prompt: "+p.SystemPrompt)
+	} else if p.SystemPromptHash != "" {
+		parts = append(parts, "prompt #"+p.SystemPromptHash)
+	}
+	if p.Latency > 0 {
+		parts = append(parts, fmt.Sprintf("%.1fs", p.Latency.Seconds()))
+	}
+	if p.InputTokens > 0 || p.OutputTokens > 0 {
+		parts = append(parts, fmt.Sprintf("%d→%d tokens", p.InputTokens, p.OutputTokens))
+	}
+	return strings.Join(parts, " • ")
+}
+
+// exportConversations writes the marked conversations, or the selected one when none are
+// marked, to the export directory. With wholeTree every branch is exported, otherwise only the active one
+func (c *ConversationListView) exportConversations(format export.Format, wholeTree bool) tea.Cmd {
+	ids := c.exportIDs()
+	if len(ids) == 0 {
+		return nil
+	}
+
 	c.list.Title = "Exporting"
-	
-	// Create a command to reset the title after 1 second
 	resetCmd := tea.Tick(time.Second, func(t time.Time) tea.Msg {
 		return ResetTitleMsg{}
 	})
 
-	// Get user's home directory
-	homeDir, err := os.UserHomeDir()
+	dir, err := export.Dir(c.config.Settings.ExportDir)
 	if err != nil {
-		return nil, fmt.Errorf("error getting home directory: %w", err)
+		c.viewport.SetContent(fmt.Sprintf("Failed to export: %v", err))
+		return resetCmd
 	}
 
-	// Get the conversation data
-	conv, err := c.db.ExportConversation(id)
-	if err != nil {
-		return nil, fmt.Errorf("error exporting conversation: %w", err)
-	}
-
-	// Create the export data structure
-	exportData := struct {
-		ID        string    `json:"id"`
-		Title     string    `json:"title"`
-		Provider  string    `json:"provider"`
-		Model     string    `json:"model"`
-		CreatedAt time.Time `json:"created_at"`
-		UpdatedAt time.Time `json:"updated_at"`
-		Messages  []struct {
-			Role      string    `json:"role"`
-			Content   string    `json:"content"`
-			Timestamp time.Time `json:"timestamp"`
-		} `json:"messages"`
-	}{
-		ID:        conv.ID,
-		Title:     conv.Title,
-		Provider:  conv.Provider,
-		Model:     conv.Model,
-		CreatedAt: conv.CreatedAt,
-		UpdatedAt: conv.UpdatedAt,
-		Messages:  make([]struct {
-			Role      string    `json:"role"`
-			Content   string    `json:"content"`
-			Timestamp time.Time `json:"timestamp"`
-		}, len(conv.Messages)),
-	}
-
-	// Convert messages
-	for i, msg := range conv.Messages {
-		exportData.Messages[i] = struct {
-			Role      string    `json:"role"`
-			Content   string    `json:"content"`
-			Timestamp time.Time `json:"timestamp"`
-		}{
-			Role:      msg.Role,
-			Content:   msg.Content,
-			Timestamp: msg.CreatedAt,
-		}
-	}
-
-	// Create JSON data
-	jsonData, err := json.MarshalIndent(exportData, "", "    ")
+	conversations := make([]*database.Conversation, 0, len(ids))
+	for _, id := range ids {
+		conv, err := c.db.ExportConversation(id, wholeTree)
+		if err != nil {
+			c.viewport.SetContent(fmt.Sprintf("Failed to export: %v", err))
+			return resetCmd
+		}
+		conversations = append(conversations, conv)
+	}
+
+	paths, err := export.WriteFiles(dir, format, conversations, exportOptions(c.config))
 	if err != nil {
-		return nil, fmt.Errorf("error marshaling JSON: %w", err)
+		c.viewport.SetContent(fmt.Sprintf("Failed to export: %v", err))
+		return resetCmd
 	}
 
-	// Create filename using conversation title (sanitized) and timestamp
-	sanitizedTitle := strings.Map(func(r rune) rune {
-		if unicode.IsLetter(r) || unicode.IsNumber(r) || r == '-' || r == '_' {
-			return r
+	if len(paths) == 1 {
+		c.list.Title = "Exported " + filepath.Base(paths[0])
+	} else {
+		c.list.Title = fmt.Sprintf("Exported %d %s files", len(paths), format.Extension())
+	}
+	c.clearMarks()
+	return resetCmd
+}
+
+// exportIDs returns the conversations to export, the marked ones in list order or else the selected one
+func (c *ConversationListView) exportIDs() []string {
+	if len(c.marked) == 0 {
+		if selected, ok := c.list.SelectedItem().(ConversationItem); ok {
+			return []string{selected.id}
 		}
-		return '-'
-	}, conv.Title)
-	
-	filename := fmt.Sprintf("%s/Downloads/conversation-%s-%s.json",
-		homeDir,
-		sanitizedTitle,
-		time.Now().Format("2006-01-02-150405"))
+		return nil
+	}
 
-	// Write the file
-	if err := os.WriteFile(filename, jsonData, 0644); err != nil {
-		return nil, fmt.Errorf("error writing file: %w", err)
+	var ids []string
+	listed := make(map[string]bool)
+	for _, item := range c.list.Items() {
+		id := item.(ConversationItem).id
+		if c.marked[id] {
+			ids = append(ids, id)
+			listed[id] = true
+		}
 	}
+	// Marked conversations a search has hidden since
+	var hidden []string
+	for id := range c.marked {
+		if !listed[id] {
+			hidden = append(hidden, id)
+		}
+	}
+	sort.Strings(hidden)
+	return append(ids, hidden...)
+}
+
+// exportOptions returns the export options for the current config and theme
+func exportOptions(cfg *config.Config) export.Options {
+	prompts := make(map[string]string)
+	for _, prompt := range cfg.SystemPrompts {
+		prompts[prompt.Title] = prompt.Content
+	}
+	t := theme.CurrentTheme
+	return export.Options{
+		Username:      cfg.Settings.Username,
+		SystemPrompts: prompts,
+		Colors: export.Colors{
+			Text:      string(t.Text.GetColor()),
+			Heading:   string(t.Primary.GetColor()),
+			Muted:     string(t.Message.Timestamp.GetColor()),
+			User:      string(t.Message.UserText.GetColor()),
+			Assistant: string(t.Message.AIText.GetColor()),
+			Accent:    string(t.Accent.GetColor()),
+			Border:    string(t.Border.Normal.GetColor()),
+		},
+	}
+}
+
+// toggleMark marks or unmarks the selected conversation for export
+func (c *ConversationListView) toggleMark() {
+	selected, ok := c.list.SelectedItem().(ConversationItem)
+	if !ok {
+		return
+	}
+	if c.marked[selected.id] {
+		delete(c.marked, selected.id)
+	} else {
+		c.marked[selected.id] = true
+	}
+	selected.marked = c.marked[selected.id]
+	c.list.SetItem(c.list.Index(), selected)
+	c.list.Title = c.title()
+}
 
-	return resetCmd, nil
+// clearMarks unmarks every conversation
+func (c *ConversationListView) clearMarks() {
+	if len(c.marked) == 0 {
+		return
+	}
+	c.marked = make(map[string]bool)
+	c.setItems(c.list.Items())
+}
+
+// setItems shows items in the list, keeping their marks
+func (c *ConversationListView) setItems(items []list.Item) {
+	for i, item := range items {
+		conv := item.(ConversationItem)
+		conv.marked = c.marked[conv.id]
+		items[i] = conv
+	}
+	c.list.SetItems(items)
+}
+
+// updateExport handles keys while the export format picker is shown
+// Left and right pick a format, enter or its number exports and esc cancels
+func (c *ConversationListView) updateExport(msg tea.KeyMsg) (*ConversationListView, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		c.focused = "list"
+	case "left", "h", "shift+tab":
+		c.exportFormat = (c.exportFormat + len(export.Formats) - 1) % len(export.Formats)
+	case "right", "l", "tab":
+		c.exportFormat = (c.exportFormat + 1) % len(export.Formats)
+	case "enter":
+		c.focused = "list"
+		return c, c.exportConversations(export.Formats[c.exportFormat], false)
+	default:
+		if k := msg.String(); len(k) == 1 && k[0] >= '1' && int(k[0]-'0') <= len(export.Formats) {
+			c.exportFormat = int(k[0] - '1')
+			c.focused = "list"
+			return c, c.exportConversations(export.Formats[c.exportFormat], false)
+		}
+	}
+	return c, nil
+}
+
+// exportPickerView shows the export formats with the picked one highlighted
+func (c *ConversationListView) exportPickerView() string {
+	label := "Export as:"
+	if len(c.marked) > 0 {
+		label = fmt.Sprintf("Export %d as:", len(c.marked))
+	}
+	pickedStyle := lipgloss.NewStyle().
+		Foreground(theme.CurrentTheme.Primary.GetColor()).
+		Bold(true).
+		Underline(true)
+	otherStyle := lipgloss.NewStyle().
+		Foreground(theme.CurrentTheme.Secondary.GetColor())
+
+	formats := make([]string, len(export.Formats))
+	for i, format := range export.Formats {
+		if i == c.exportFormat {
+			formats[i] = pickedStyle.Render(format.Extension())
+		} else {
+			formats[i] = otherStyle.Render(format.Extension())
+		}
+	}
+	return label + " " + strings.Join(formats, " ")
 }
 
 func (c *ConversationListView) Update(msg tea.Msg) (*ConversationListView, tea.Cmd) {
@@ -315,7 +810,14 @@ func (c *ConversationListView) Update(msg tea.Msg) (*ConversationListView, tea.C
 
 	switch msg := msg.(type) {
 	case ResetTitleMsg:
-		c.list.Title = "Conversations"
+		c.list.Title = c.title()
+		return c, nil
+
+	case undoExpiredMsg:
+		if c.undoID == msg.id {
+			c.undoID = ""
+			c.list.Title = c.title()
+		}
 		return c, nil
 
 	case tea.MouseMsg:
@@ -343,7 +845,10 @@ func (c *ConversationListView) Update(msg tea.Msg) (*ConversationListView, tea.C
 							if msgIndex < len(c.messages) {
 								msg := c.messages[msgIndex]
 								if err := clipboard.WriteAll(msg.Content); err != nil {
-									fmt.Printf("Error copying to clipboard: %v\n", err)
+									c.list.Title = "Failed to copy"
+									cmds = append(cmds, tea.Tick(time.Second, func(time.Time) tea.Msg {
+										return ResetTitleMsg{}
+									}))
 								}
 							}
 						}
@@ -353,6 +858,31 @@ func (c *ConversationListView) Update(msg tea.Msg) (*ConversationListView, tea.C
 		}
 
 	case tea.KeyMsg:
+		if c.focused == "search" {
+			return c.updateSearch(msg)
+		}
+		if c.focused == "tags" {
+			return c.updateTags(msg)
+		}
+		if c.focused == "export" {
+			return c.updateExport(msg)
+		}
+
+		// ctrl+d in the trash only deletes forever when pressed twice in a row
+		if !key.Matches(msg, c.keys.Delete) && c.purgeID != "" {
+			c.purgeID = ""
+			c.list.Title = c.title()
+		}
+
+		if key.Matches(msg, c.keys.Undo) && c.undoID != "" {
+			return c, c.undoDelete()
+		}
+
+		if key.Matches(msg, c.keys.Trash) {
+			c.toggleTrash()
+			return c, nil
+		}
+
 		// Handle tab key for focus switching
 		if msg.String() == "tab" {
 			if c.focused == "list" {
@@ -363,6 +893,53 @@ func (c *ConversationListView) Update(msg tea.Msg) (*ConversationListView, tea.C
 			return c, nil
 		}
 
+		if key.Matches(msg, c.keys.Search) && !c.trash {
+			c.focused = "search"
+			return c, c.search.Focus()
+		}
+
+		if key.Matches(msg, c.keys.FilterTag) && !c.trash {
+			value := strings.TrimSpace(c.search.Value())
+			if value != "" {
+				value += " "
+			}
+			c.search.SetValue(value + "#")
+			c.search.CursorEnd()
+			c.focused = "search"
+			return c, c.search.Focus()
+		}
+
+		if key.Matches(msg, c.keys.Pin) && !c.trash {
+			if selected, ok := c.list.SelectedItem().(ConversationItem); ok {
+				if err := c.db.SetConversationPinned(selected.id, !selected.pinned); err != nil {
+					c.viewport.SetContent(fmt.Sprintf("Failed to pin conversation: %v", err))
+					return c, nil
+				}
+				c.refresh()
+				c.selectConversation(selected.id)
+			}
+			return c, nil
+		}
+
+		if key.Matches(msg, c.keys.EditTags) && !c.trash {
+			if selected, ok := c.list.SelectedItem().(ConversationItem); ok {
+				return c, c.startTagEdit(selected.id)
+			}
+			return c, nil
+		}
+
+		// Escape clears an active search before leaving the list
+		if key.Matches(msg, c.keys.Back) && c.query != "" {
+			c.clearSearch()
+			return c, nil
+		}
+
+		// and leaves the trash
+		if key.Matches(msg, c.keys.Back) && c.trash {
+			c.toggleTrash()
+			return c, nil
+		}
+
 		// Handle our key bindings first
 		if key.Matches(msg, c.keys.Back) {
 			return c, func() tea.Msg {
@@ -371,28 +948,48 @@ func (c *ConversationListView) Update(msg tea.Msg) (*ConversationListView, tea.C
 		}
 
 		if key.Matches(msg, c.keys.Delete) {
-			if len(c.list.Items()) > 0 {
-				selected := c.list.SelectedItem().(ConversationItem)
-				if err := c.db.DeleteConversation(selected.id); err == nil {
-					c.loadConversations()
-					c.messages = nil
-					c.viewport.SetContent("Select a conversation to view messages")
+			if selected, ok := c.list.SelectedItem().(ConversationItem); ok {
+				if c.trash {
+					return c, c.purgeConversation(selected.id)
+				}
+				return c, c.deleteConversation(selected.id)
+			}
+			return c, nil
+		}
+
+		if key.Matches(msg, c.keys.Open) && c.trash {
+			if selected, ok := c.list.SelectedItem().(ConversationItem); ok {
+				return c, c.restoreConversation(selected.id)
+			}
+			return c, nil
+		}
+
+		if key.Matches(msg, c.keys.Open) || key.Matches(msg, c.keys.OpenWithModel) {
+			if selected, ok := c.list.SelectedItem().(ConversationItem); ok {
+				open := OpenConversationMsg{id: selected.id, restoreModel: key.Matches(msg, c.keys.OpenWithModel)}
+				return c, func() tea.Msg {
+					return open
 				}
 			}
+			return c, nil
+		}
+
+		if key.Matches(msg, c.keys.Mark) && c.focused == "list" {
+			c.toggleMark()
+			return c, nil
 		}
 
 		if key.Matches(msg, c.keys.Export) {
 			if len(c.list.Items()) > 0 {
-				selected := c.list.SelectedItem().(ConversationItem)
-				if cmd, err := c.exportConversation(selected.id); err != nil {
-					fmt.Printf("Error exporting conversation: %v\n", err)
-				} else {
-					return c, cmd
-				}
+				c.focused = "export"
 			}
 			return c, nil
 		}
 
+		if key.Matches(msg, c.keys.ExportTree) {
+			return c, c.exportConversations(export.JSON, true)
+		}
+
 		// Only pass key events to the focused component
 		if c.focused == "list" {
 			var listCmd tea.Cmd
@@ -405,15 +1002,196 @@ func (c *ConversationListView) Update(msg tea.Msg) (*ConversationListView, tea.C
 		}
 	}
 
-	// If the selected conversation changed, load its messages
+	c.syncSelection()
+
+	return c, tea.Batch(cmds...)
+}
+
+// deleteConversation moves a conversation to the trash, and lets the Undo key restore it for a moment
+func (c *ConversationListView) deleteConversation(id string) tea.Cmd {
+	if err := c.db.DeleteConversation(id); err != nil {
+		c.viewport.SetContent(fmt.Sprintf("Failed to delete conversation: %v", err))
+		return nil
+	}
+	c.refresh()
+	c.selected = -1
+	c.syncSelection()
+
+	c.undoID = id
+	c.list.Title = "Moved to trash · u to undo"
+	return tea.Tick(undoTimeout, func(time.Time) tea.Msg {
+		return undoExpiredMsg{id: id}
+	})
+}
+
+// undoDelete takes the conversation deleted last out of the trash again
+func (c *ConversationListView) undoDelete() tea.Cmd {
+	id := c.undoID
+	c.undoID = ""
+	if err := c.db.RestoreConversation(id); err != nil {
+		c.viewport.SetContent(fmt.Sprintf("Failed to restore conversation: %v", err))
+		return nil
+	}
+	c.refresh()
+	c.selectConversation(id)
+	return nil
+}
+
+// restoreConversation takes a conversation out of the trash
+func (c *ConversationListView) restoreConversation(id string) tea.Cmd {
+	if err := c.db.RestoreConversation(id); err != nil {
+		c.viewport.SetContent(fmt.Sprintf("Failed to restore conversation: %v", err))
+		return nil
+	}
+	c.refresh()
+	c.selected = -1
+	c.syncSelection()
+	c.list.Title = "Restored"
+	return tea.Tick(time.Second, func(time.Time) tea.Msg {
+		return ResetTitleMsg{}
+	})
+}
+
+// purgeConversation permanently deletes a conversation in the trash on the second ctrl+d
+func (c *ConversationListView) purgeConversation(id string) tea.Cmd {
+	if c.purgeID != id {
+		c.purgeID = id
+		c.list.Title = "ctrl+d again to delete forever"
+		return nil
+	}
+	c.purgeID = ""
+	if err := c.db.PurgeConversation(id); err != nil {
+		c.viewport.SetContent(fmt.Sprintf("Failed to delete conversation: %v", err))
+		return nil
+	}
+	c.refresh()
+	c.selected = -1
+	c.syncSelection()
+	return nil
+}
+
+// toggleTrash switches between the conversations and the trash
+func (c *ConversationListView) toggleTrash() {
+	c.trash = !c.trash
+	c.undoID = ""
+	c.purgeID = ""
+	c.marked = make(map[string]bool)
+	if c.query != "" {
+		c.search.SetValue("")
+		c.query = ""
+	}
+	c.loadConversations()
+	c.list.Select(0)
+	c.selected = 0
+}
+
+// selectConversation moves the selection to the conversation with the given ID if it's listed
+func (c *ConversationListView) selectConversation(id string) {
+	for i, item := range c.list.Items() {
+		if item.(ConversationItem).id == id {
+			c.list.Select(i)
+			break
+		}
+	}
+	c.selected = -1 // The list was reloaded, so show the selected conversation even if its index didn't change
+	c.syncSelection()
+}
+
+// syncSelection loads the messages of the selected conversation when the selection changed
+func (c *ConversationListView) syncSelection() {
 	newSelected := c.list.Index()
 	if newSelected != c.selected && len(c.list.Items()) > 0 {
 		selected := c.list.SelectedItem().(ConversationItem)
-		c.loadMessages(selected.id)
+		c.loadMessages(selected.id, selected.matchID)
 	}
 	c.selected = newSelected
 
-	return c, tea.Batch(cmds...)
+	// Load the next page before the selection reaches the end of the list
+	if c.hasMore && c.query == "" && !c.trash && newSelected >= len(c.list.Items())-c.list.Paginator.PerPage {
+		c.loadMore()
+	}
+}
+
+// updateSearch handles keys while the search box is focused
+// The results update as you type, up and down move through them and enter jumps to the match
+func (c *ConversationListView) updateSearch(msg tea.KeyMsg) (*ConversationListView, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		c.clearSearch()
+		return c, nil
+	case "enter":
+		c.search.Blur()
+		c.focused = "messages"
+		if c.query == "" {
+			c.focused = "list"
+		}
+		return c, nil
+	case "tab":
+		c.search.Blur()
+		c.focused = "list"
+		return c, nil
+	case "up", "down":
+		c.list, _ = c.list.Update(msg)
+		c.syncSelection()
+		return c, nil
+	}
+
+	var cmd tea.Cmd
+	c.search, cmd = c.search.Update(msg)
+	if strings.TrimSpace(c.search.Value()) != c.query {
+		c.runSearch(c.search.Value())
+		c.selected = c.list.Index()
+	}
+	return c, cmd
+}
+
+// startTagEdit shows the tags of a conversation in the tag box for editing
+func (c *ConversationListView) startTagEdit(id string) tea.Cmd {
+	tags, err := c.db.GetConversationTags(id)
+	if err != nil {
+		c.viewport.SetContent(fmt.Sprintf("Failed to load tags: %v", err))
+		return nil
+	}
+	c.tagging = id
+	c.tagInput.SetValue(strings.Join(tags, " "))
+	c.tagInput.CursorEnd()
+	c.focused = "tags"
+	return c.tagInput.Focus()
+}
+
+// updateTags handles keys while the tag box is focused
+// Enter saves the tags and esc discards the changes
+func (c *ConversationListView) updateTags(msg tea.KeyMsg) (*ConversationListView, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		c.stopTagEdit()
+		return c, nil
+	case "enter":
+		err := c.db.SetConversationTags(c.tagging, database.ParseTags(c.tagInput.Value()))
+		c.stopTagEdit()
+		c.refresh()
+		if err != nil {
+			c.viewport.SetContent(fmt.Sprintf("Failed to save tags: %v", err))
+		}
+		return c, nil
+	}
+
+	var cmd tea.Cmd
+	c.tagInput, cmd = c.tagInput.Update(msg)
+	return c, cmd
+}
+
+// stopTagEdit hides the tag box
+func (c *ConversationListView) stopTagEdit() {
+	c.tagInput.Blur()
+	c.tagInput.SetValue("")
+	c.tagging = ""
+	c.focused = "list"
+}
+
+// Searching reports whether the search box, tag box or export picker has focus and should receive all keys
+func (c *ConversationListView) Searching() bool {
+	return c.focused == "search" || c.focused == "tags" || c.focused == "export"
 }
 
 func (c ConversationListView) View() string {
@@ -426,7 +1204,7 @@ func (c ConversationListView) View() string {
 		Height(34)
 
 	// Highlight the focused container's border
-	if c.focused == "list" {
+	if c.focused == "list" || c.focused == "search" || c.focused == "tags" || c.focused == "export" {
 		listStyle = listStyle.BorderForeground(theme.CurrentTheme.Border.Active.GetColor())
 	}
 
@@ -438,9 +1216,17 @@ func (c ConversationListView) View() string {
 	}
 	c.viewport.Style = vpStyle
 
+	// The tag box and export picker take the place of the search box while they're used
+	inputView := c.search.View()
+	if c.focused == "tags" {
+		inputView = c.tagInput.View()
+	} else if c.focused == "export" {
+		inputView = c.exportPickerView()
+	}
+
 	containers := lipgloss.JoinHorizontal(
 		lipgloss.Left,
-		listStyle.Render(c.list.View()),
+		listStyle.Render(lipgloss.JoinVertical(lipgloss.Left, inputView, "", c.list.View())),
 		c.viewport.View(),
 	)
 
@@ -456,7 +1242,7 @@ func (c ConversationListView) View() string {
 func (c *ConversationListView) SetSize(width, height int) {
 	c.width = width
 	c.height = height
-	c.list.SetSize(30, height - 4)
+	c.list.SetSize(30, height - 6)
 	
 	c.viewport.Width = width - 37
 	c.viewport.Height = height
@@ -0,0 +1,112 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/tedfulk/goatmeal/config"
+	"github.com/tedfulk/goatmeal/services/export"
+	"github.com/tedfulk/goatmeal/ui/theme"
+)
+
+// ExportSettings changes the directory conversations are exported to
+type ExportSettings struct {
+	textInput textinput.Model
+	config    *config.Config
+	status    string
+	width     int
+	height    int
+}
+
+func NewExportSettings(cfg *config.Config) ExportSettings {
+	ti := textinput.New()
+	ti.Placeholder = export.DefaultDir
+	ti.Width = 50
+	ti.Focus()
+
+	return ExportSettings{
+		textInput: ti,
+		config:    cfg,
+	}
+}
+
+func (e ExportSettings) Update(msg tea.Msg) (ExportSettings, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.Type {
+		case tea.KeyEsc:
+			e.textInput.SetValue("")
+			e.status = ""
+			return e, func() tea.Msg {
+				return SetViewMsg{view: "settings"}
+			}
+		case tea.KeyEnter:
+			// An empty directory goes back to the default
+			dir := strings.TrimSpace(e.textInput.Value())
+			if _, err := export.Dir(dir); err != nil {
+				e.status = "Error: " + err.Error()
+				return e, nil
+			}
+			e.config.Settings.ExportDir = dir
+			manager, err := config.NewManager()
+			if err == nil {
+				err = manager.UpdateSettings(e.config.Settings)
+			}
+			if err != nil {
+				e.status = "Error: " + err.Error()
+				return e, nil
+			}
+			e.status = "Saved"
+			e.textInput.SetValue("")
+			return e, nil
+		}
+	}
+
+	var cmd tea.Cmd
+	e.textInput, cmd = e.textInput.Update(msg)
+	return e, cmd
+}
+
+func (e ExportSettings) View() string {
+	menuStyle := theme.BaseStyle.Menu.
+		BorderForeground(theme.CurrentTheme.Primary.GetColor())
+
+	titleStyle := theme.BaseStyle.Title.
+		Foreground(theme.CurrentTheme.Primary.GetColor())
+
+	helpStyle := lipgloss.NewStyle().
+		Foreground(theme.CurrentTheme.Secondary.GetColor()).
+		Align(lipgloss.Center)
+
+	current := e.config.Settings.ExportDir
+	if current == "" {
+		current = export.DefaultDir + " (default)"
+	}
+
+	menuContent := lipgloss.JoinVertical(
+		lipgloss.Left,
+		titleStyle.Render("Export Directory"),
+		"",
+		"Current directory: "+current,
+		"",
+		e.textInput.View(),
+		"",
+		lipgloss.NewStyle().Width(56).Render(e.status),
+		"",
+		helpStyle.Render("      enter: save (empty for default) • esc: back"),
+	)
+
+	return lipgloss.Place(
+		e.width,
+		e.height,
+		lipgloss.Center,
+		lipgloss.Center,
+		menuStyle.Render(menuContent),
+	)
+}
+
+func (e *ExportSettings) SetSize(width, height int) {
+	e.width = width
+	e.height = height
+}
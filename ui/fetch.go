@@ -0,0 +1,103 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/google/uuid"
+	"github.com/tedfulk/goatmeal/database"
+	"github.com/tedfulk/goatmeal/services/search"
+	"github.com/tedfulk/goatmeal/utils/prompts"
+)
+
+const (
+	// fetchPrefix marks the user message of a /fetch command
+	fetchPrefix = "🌐 Fetch: "
+
+	// fetchContentLimit is the number of characters of a fetched page kept in the chat and sent to the model
+	fetchContentLimit = 20000
+)
+
+// fetchPage downloads a page and shows its content, or has the current model answer a question about it
+// args is the URL optionally followed by the question
+func (a *App) fetchPage(args string) tea.Cmd {
+	pageURL, question, _ := strings.Cut(strings.TrimSpace(args), " ")
+	question = strings.TrimSpace(question)
+	if pageURL == "" {
+		a.input.Reset()
+		return nil
+	}
+
+	content := fetchPrefix + pageURL
+	if question != "" {
+		content += "\n" + question
+	}
+	userMsg := NewMessage(a.nextMessageID, UserMessage, content, a.config, a.getNextCodeBlockNumber)
+	a.messages = append(a.messages, userMsg)
+	a.nextMessageID++
+
+	// If this is the first message, generate a title and create conversation
+	if len(a.messages) == 1 {
+		titleQuery := question
+		if titleQuery == "" {
+			titleQuery = pageURL
+		}
+		go a.generateTitle(titleQuery)
+		a.currentConversationID = uuid.New().String()
+	}
+
+	a.updateConversationView()
+	a.input.Reset()
+	a.statusBar.SetLoading(true)
+
+	go func() {
+		defer func() {
+			a.statusBar.SetLoading(false)
+		}()
+
+		page, err := search.FetchPage(pageURL, a.config.APIKeys)
+		if err != nil {
+			errorMsg := NewMessage(a.nextMessageID, ProviderMessage, fmt.Sprintf("Error fetching page: %v", err), a.config, a.getNextCodeBlockNumber)
+			a.messages = append(a.messages, errorMsg)
+			a.nextMessageID++
+			a.saveExchange(2, a.config.CurrentProvider, a.config.CurrentModel)
+			a.updateConversationView()
+			return
+		}
+
+		pageContent := truncateText(page.Content, fetchContentLimit)
+
+		// Ask the model before adding the page so the page prompt replaces the question in history
+		var response string
+		var provenance database.Provenance
+		if question != "" {
+			response, provenance, _ = a.sendToProvider(prompts.GetPageQuestionPrompt(page.URL, pageContent, question))
+		}
+
+		title := page.Title
+		if title == "" {
+			title = page.URL
+		}
+		pageMsg := NewMessage(a.nextMessageID, SearchMessage, fmt.Sprintf("### [%s](%s)\n\n%s", title, page.URL, pageContent), a.config, a.getNextCodeBlockNumber)
+		pageMsg.Source = search.DisplayName(page.Source)
+		a.messages = append(a.messages, pageMsg)
+		a.nextMessageID++
+
+		if question == "" {
+			a.saveExchange(2, a.config.CurrentProvider, a.config.CurrentModel)
+			a.updateConversationView()
+			return
+		}
+
+		answerMsg := NewMessage(a.nextMessageID, ProviderMessage, response, a.config, a.getNextCodeBlockNumber)
+		answerMsg.Provenance = provenance
+		a.messages = append(a.messages, answerMsg)
+		a.nextMessageID++
+
+		a.saveExchange(3, a.config.CurrentProvider, a.config.CurrentModel)
+		a.updateConversationView()
+	}()
+
+	return a.statusBar.spinner.Tick
+}
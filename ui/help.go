@@ -38,11 +38,27 @@ func NewHelpView() *HelpView {
 * **/c[n]**: Copy message number 'n' to clipboard (e.g., /c1)
 * **/b[n]**: Copy code block number 'n' to clipboard (e.g., /b1)
 * **/s[n]**: Speak message number 'n' (e.g., /s1)
+* **/edit N**: Edit your message N and send it as a new branch
+* **/regen [provider/model]**: Answer your last message again as another version of the reply
+* **/pin**, **/unpin**: Pin the conversation to the top and keep it from cleanup
+* **/tag [name] [-name]**: Show, add or remove the conversation's tags
+* **/v[n]**: Show the next version of message 'n' (**/v3 2** for version 2)
+* **/open N**: Open search result N in your browser (e.g., /open 2)
+* **/links**: List every link in the conversation to open or copy
 * **ctrl+q**: Stop current speech playback
 
 ## Web Search Commands
 * **/web query**: Search for information
 * **/web query +domain.com**: Search with specific domain
+* **/web query -domain.com**: Exclude a domain from results
+* **/web query @go**: Apply the search preset named go (Settings → Search Presets)
+* **/web query --news --days 3**: News from the last 3 days
+* **/web query --time week --deep --max 10**: Time range, search depth and result count
+* **/web query --raw --images**: Include page content and images
+* **/web! query**: Search again instead of using cached results
+* **/web@searx query**: Search with a specific backend (tavily, searx, brave, ddg)
+* **/ask-web query**: Answer from web search results with numbered citations
+* **/fetch url [question]**: Show a page's content, or answer a question about it
 * **/webe query**: Enhanced web search with AI optimization
 * **/webe query +domain.com**: Enhanced domain-specific search
 * **/epq query**: Enhanced programming query with AI optimization
@@ -53,8 +69,17 @@ Enhanced programming query mode (💻+) uses AI to optimize programming-related
 
 ## Conversation List
 * **tab**: Switch focus between list and messages
-* **ctrl+d**: Delete selected conversation
-* **ctrl+e**: Export conversation as JSON
+* **enter**: Continue the selected conversation
+* **ctrl+o**: Continue it with the model it was using
+* **/** or **ctrl+f**: Search messages and titles, **esc** to clear
+* **#**: Filter by tag, e.g. **#go generics**
+* **p**: Pin or unpin the selected conversation
+* **t**: Edit the selected conversation's tags
+* **ctrl+d**: Move selected conversation to the trash, **u** to undo
+* **T**: Show the trash, **enter** restores and **ctrl+d** twice deletes forever
+* **space**: Mark conversations to export together
+* **ctrl+e**: Export as Markdown, HTML, text, JSONL or JSON
+* **E**: Export every branch as JSON
 * **esc**: Return to chat
 
 ## Settings Menu
@@ -0,0 +1,137 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/tedfulk/goatmeal/database"
+	"github.com/tedfulk/goatmeal/services/importer"
+	"github.com/tedfulk/goatmeal/ui/theme"
+)
+
+// importDoneMsg reports the result of an import started from the import view
+type importDoneMsg struct {
+	result importer.Result
+	err    error
+}
+
+// ImportView asks for an export file and imports its conversations
+type ImportView struct {
+	textInput textinput.Model
+	db        *database.DB
+	status    string
+	importing bool
+	width     int
+	height    int
+}
+
+func NewImportView(db *database.DB) *ImportView {
+	ti := textinput.New()
+	ti.Placeholder = "~/Downloads/conversations.json"
+	ti.Width = 50
+
+	return &ImportView{
+		textInput: ti,
+		db:        db,
+	}
+}
+
+// Reset clears the view for a new import
+func (v *ImportView) Reset() tea.Cmd {
+	v.textInput.SetValue("")
+	v.status = ""
+	return v.textInput.Focus()
+}
+
+func (v *ImportView) Update(msg tea.Msg) (*ImportView, tea.Cmd) {
+	switch msg := msg.(type) {
+	case importDoneMsg:
+		v.importing = false
+		if msg.err != nil {
+			v.status = "Error: " + msg.err.Error()
+		} else {
+			v.status = msg.result.String()
+			v.textInput.SetValue("")
+		}
+		return v, nil
+
+	case tea.KeyMsg:
+		switch msg.Type {
+		case tea.KeyEsc:
+			return v, func() tea.Msg {
+				return SetViewMsg{view: "chat"}
+			}
+		case tea.KeyEnter:
+			path := expandHome(strings.TrimSpace(v.textInput.Value()))
+			if path == "" || v.importing {
+				return v, nil
+			}
+			v.importing = true
+			v.status = "Importing..."
+			db := v.db
+			return v, func() tea.Msg {
+				result, err := importer.ImportFile(db, path)
+				return importDoneMsg{result: result, err: err}
+			}
+		}
+	}
+
+	var cmd tea.Cmd
+	v.textInput, cmd = v.textInput.Update(msg)
+	return v, cmd
+}
+
+func (v *ImportView) View() string {
+	menuStyle := theme.BaseStyle.Menu.
+		BorderForeground(theme.CurrentTheme.Primary.GetColor())
+
+	titleStyle := theme.BaseStyle.Title.
+		Foreground(theme.CurrentTheme.Primary.GetColor())
+
+	helpStyle := lipgloss.NewStyle().
+		Foreground(theme.CurrentTheme.Secondary.GetColor()).
+		Align(lipgloss.Center)
+
+	menuContent := lipgloss.JoinVertical(
+		lipgloss.Left,
+		titleStyle.Render("Import Conversations"),
+		"",
+		"ChatGPT or Claude.ai export (zip or conversations.json),",
+		"or a goatmeal JSON export or archive:",
+		"",
+		v.textInput.View(),
+		"",
+		lipgloss.NewStyle().Width(56).Render(v.status),
+		"",
+		helpStyle.Render("      enter: import • esc: back"),
+	)
+
+	return lipgloss.Place(
+		v.width,
+		v.height,
+		lipgloss.Center,
+		lipgloss.Center,
+		menuStyle.Render(menuContent),
+	)
+}
+
+func (v *ImportView) SetSize(width, height int) {
+	v.width = width
+	v.height = height
+}
+
+// expandHome replaces a leading ~ with the home directory
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~"))
+}
@@ -0,0 +1,217 @@
+package ui
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/atotto/clipboard"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/tedfulk/goatmeal/services/search"
+	"github.com/tedfulk/goatmeal/ui/theme"
+	"github.com/tedfulk/goatmeal/utils/browser"
+)
+
+var (
+	// markdownLinkRe matches markdown links and images with an http(s) target
+	markdownLinkRe = regexp.MustCompile(`!?\[([^\]]*)\]\((https?://[^\s)]+)\)`)
+
+	// bareURLRe matches URLs written without markdown link syntax
+	bareURLRe = regexp.MustCompile(`https?://[^\s<>()\[\]"'` + "`" + `]+`)
+)
+
+// LinkItem is a URL found in the conversation
+type LinkItem struct {
+	number    int
+	title     string
+	url       string
+	messageID int
+}
+
+func (i LinkItem) Title() string {
+	if i.title == "" || i.title == i.url {
+		return fmt.Sprintf("%d. %s", i.number, i.url)
+	}
+	return fmt.Sprintf("%d. %s", i.number, i.title)
+}
+func (i LinkItem) Description() string { return fmt.Sprintf("/c%d • %s", i.messageID, i.url) }
+func (i LinkItem) FilterValue() string { return i.title + " " + i.url }
+
+// LinksKeyMap defines the key bindings of the links view
+type LinksKeyMap struct {
+	Open key.Binding
+	Copy key.Binding
+	Back key.Binding
+}
+
+var DefaultLinksKeyMap = LinksKeyMap{
+	Open: key.NewBinding(
+		key.WithKeys("enter", "o"),
+		key.WithHelp("enter/o", "open in browser"),
+	),
+	Copy: key.NewBinding(
+		key.WithKeys("c", "y"),
+		key.WithHelp("c/y", "copy URL"),
+	),
+	Back: key.NewBinding(
+		key.WithKeys("esc"),
+		key.WithHelp("esc", "back to chat"),
+	),
+}
+
+// LinksView lists every URL in the current conversation
+type LinksView struct {
+	list   list.Model
+	status string
+	width  int
+	height int
+}
+
+func NewLinksView() *LinksView {
+	l := list.New([]list.Item{}, list.NewDefaultDelegate(), 0, 0)
+	l.Title = "Links"
+	l.SetShowHelp(true)
+	l.SetFilteringEnabled(true)
+	l.Styles.Title = theme.BaseStyle.Title.
+		Foreground(theme.CurrentTheme.Primary.GetColor())
+	l.AdditionalShortHelpKeys = func() []key.Binding {
+		return []key.Binding{
+			DefaultLinksKeyMap.Open,
+			DefaultLinksKeyMap.Copy,
+			DefaultLinksKeyMap.Back,
+		}
+	}
+	l.AdditionalFullHelpKeys = l.AdditionalShortHelpKeys
+
+	return &LinksView{list: l}
+}
+
+// SetLinks replaces the listed links
+func (v *LinksView) SetLinks(links []LinkItem) {
+	items := make([]list.Item, len(links))
+	for i, link := range links {
+		items[i] = link
+	}
+	v.list.SetItems(items)
+	v.list.ResetFilter()
+	v.list.Select(0)
+	v.status = ""
+	if len(links) == 0 {
+		v.status = "No links in this conversation"
+	}
+}
+
+func (v *LinksView) Update(msg tea.Msg) (*LinksView, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok && v.list.FilterState() != list.Filtering {
+		item, selected := v.list.SelectedItem().(LinkItem)
+		switch {
+		case key.Matches(msg, DefaultLinksKeyMap.Open):
+			if selected {
+				if err := browser.Open(item.url); err != nil {
+					v.status = err.Error()
+				} else {
+					v.status = "Opened " + item.url
+				}
+			}
+			return v, nil
+		case key.Matches(msg, DefaultLinksKeyMap.Copy):
+			if selected {
+				if err := clipboard.WriteAll(item.url); err != nil {
+					v.status = fmt.Sprintf("Failed to copy: %v", err)
+				} else {
+					v.status = "Copied " + item.url
+				}
+			}
+			return v, nil
+		}
+	}
+
+	var cmd tea.Cmd
+	v.list, cmd = v.list.Update(msg)
+	return v, cmd
+}
+
+func (v *LinksView) View() string {
+	statusStyle := lipgloss.NewStyle().
+		Foreground(theme.CurrentTheme.Secondary.GetColor()).
+		Padding(0, 2)
+
+	return lipgloss.JoinVertical(
+		lipgloss.Left,
+		v.list.View(),
+		statusStyle.Render(v.status),
+	)
+}
+
+func (v *LinksView) SetSize(width, height int) {
+	v.width = width
+	v.height = height
+	v.list.SetSize(width-4, height-3)
+}
+
+// conversationLinks returns every URL in the messages, in order of first appearance
+func conversationLinks(messages []Message) []LinkItem {
+	var links []LinkItem
+	seen := make(map[string]bool)
+	add := func(title, url string, messageID int) {
+		url = strings.TrimRight(url, ".,;:!?*_")
+		if seen[url] {
+			return
+		}
+		seen[url] = true
+		links = append(links, LinkItem{number: len(links) + 1, title: strings.TrimSpace(title), url: url, messageID: messageID})
+	}
+
+	for _, msg := range messages {
+		// Markdown links first so they keep their titles, then any bare URLs left over
+		for _, match := range markdownLinkRe.FindAllStringSubmatch(msg.Content, -1) {
+			add(strings.Trim(match[1], "*_[] "), match[2], msg.ID)
+		}
+		remaining := markdownLinkRe.ReplaceAllString(msg.Content, "")
+		for _, url := range bareURLRe.FindAllString(remaining, -1) {
+			add("", url, msg.ID)
+		}
+	}
+	return links
+}
+
+// openResult opens search result n from the most recent numbered results, or a URL given directly
+func (a *App) openResult(arg string) {
+	arg = strings.TrimSpace(arg)
+	if strings.HasPrefix(arg, "http://") || strings.HasPrefix(arg, "https://") {
+		if err := browser.Open(arg); err != nil {
+			a.statusBar.SetError(err.Error())
+		}
+		return
+	}
+
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < 1 {
+		a.statusBar.SetError("Usage: /open N or /open URL")
+		return
+	}
+
+	for i := len(a.messages) - 1; i >= 0; i-- {
+		if a.messages[i].Type != SearchMessage {
+			continue
+		}
+		results := search.ParseNumberedResults(a.messages[i].Content)
+		if len(results) == 0 {
+			continue
+		}
+		if n > len(results) {
+			a.statusBar.SetError(fmt.Sprintf("The last search has %d results", len(results)))
+			return
+		}
+		if err := browser.Open(results[n-1].URL); err != nil {
+			a.statusBar.SetError(err.Error())
+		}
+		return
+	}
+
+	a.statusBar.SetError("No search results to open")
+}
@@ -33,6 +33,10 @@ func NewMenu() Menu {
 			title:       "Conversations",
 			description: "List conversation history (ctrl+l)",
 		},
+		MenuItem{
+			title:       "Import",
+			description: "Import ChatGPT, Claude.ai or goatmeal exports",
+		},
 		MenuItem{
 			title:       "Settings",
 			description: "Configure settings (ctrl+s)",
@@ -12,6 +12,7 @@ import (
 	"github.com/charmbracelet/glamour"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/tedfulk/goatmeal/config"
+	"github.com/tedfulk/goatmeal/database"
 	"github.com/tedfulk/goatmeal/ui/theme"
 	"github.com/tedfulk/goatmeal/utils/models"
 )
@@ -32,6 +33,11 @@ type Message struct {
 	Content   string
 	Timestamp time.Time
 	Config    *config.Config
+	Source    string        // Search backend that produced a search message
+	Provenance database.Provenance // Provider, model and usage of a provider message
+	DBID      string        // ID of the stored message, empty until it's saved
+	Variant   int           // Position among the versions of this message, starting at 1
+	Variants  int           // Number of versions of this message from edits and regenerations
 	codeBlocks []CodeBlock  // Store the code blocks when message is created
 }
 
@@ -59,6 +65,15 @@ func NewMessage(id int, msgType MessageType, content string, cfg *config.Config,
 	return msg
 }
 
+// Model returns the model that generated the message
+// Messages without provenance fall back to the current model
+func (m Message) Model() string {
+	if m.Provenance.Model != "" {
+		return m.Provenance.Model
+	}
+	return m.Config.CurrentModel
+}
+
 // wordWrap wraps text at the specified width
 func wordWrap(text string, width int) string {
 	words := strings.Fields(text)
@@ -89,18 +104,23 @@ func (m Message) View(width int) string {
 	if m.Type == UserMessage {
 		prefix = m.Config.Settings.Username
 	} else if m.Type == SearchMessage {
-		prefix = "Tavily"
+		prefix = m.Source
+		if prefix == "" {
+			prefix = "Tavily"
+		}
 	} else {
-		prefix = models.StripModelsPrefix(m.Config.CurrentModel)
+		prefix = models.StripModelsPrefix(m.Model())
 	}
 	
 	timestampStyle := lipgloss.NewStyle().
 		Foreground(theme.CurrentTheme.Message.Timestamp.GetColor())
 	
 	// Add speech indicator to timestamp
-	timestampStr := timestampStyle.Render(
-		fmt.Sprintf("%s • /c%d • /s%d • %s", prefix, m.ID, m.ID, m.Timestamp.Format("15:04")),
-	)
+	header := fmt.Sprintf("%s • /c%d • /s%d • %s", prefix, m.ID, m.ID, m.Timestamp.Format("15:04"))
+	if m.Variants > 1 {
+		header += fmt.Sprintf(" • < %d/%d > /v%d", m.Variant, m.Variants, m.ID)
+	}
+	timestampStr := timestampStyle.Render(header)
 
 	baseStyle := theme.BaseStyle.Message
 
@@ -1,13 +1,18 @@
 package ui
 
 import (
+	"context"
 	"fmt"
 
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/tedfulk/goatmeal/config"
 	"github.com/tedfulk/goatmeal/services/providers/model_selection"
+	"github.com/tedfulk/goatmeal/services/providers/ollama"
+	"github.com/tedfulk/goatmeal/services/search"
 	"github.com/tedfulk/goatmeal/ui/theme"
 )
 
@@ -47,13 +52,25 @@ type ModelSettings struct {
 	width        int
 	height       int
 	showModels   bool
+	selectedProvider string // Provider whose models are shown
+
+	// Ollama model management
+	ollamaMode    string // "", "pull", "details" or "running"
+	ollamaContent string
+	ollamaStatus  string
+	deleteID      string // Model ctrl+d deletes when pressed again
+	pullInput     textinput.Model
+	pulling       bool
+	pullProgress  ollama.PullProgress
+	pullUpdates   chan tea.Msg
+	cancelPull    context.CancelFunc
 }
 
 // Add helper method to refresh provider list
 func (m *ModelSettings) refreshProviderList() {
 	var items []list.Item
 	for provider, key := range m.config.APIKeys {
-		if key != "" && provider != "tavily" {
+		if key != "" && !search.IsBackend(provider) {
 			items = append(items, ModelProviderMenuItem{
 				provider:  provider,
 				isCurrent: provider == m.config.CurrentProvider,
@@ -67,8 +84,8 @@ func NewModelSettings(cfg *config.Config) ModelSettings {
 	// Create list items for providers with API keys
 	var items []list.Item
 	for provider, key := range cfg.APIKeys {
-		// Skip tavily and only add providers with valid API keys
-		if key != "" && provider != "tavily" {
+		// Skip search backends and only add providers with valid API keys
+		if key != "" && !search.IsBackend(provider) {
 			items = append(items, ModelProviderMenuItem{
 				provider:  provider,
 				isCurrent: provider == cfg.CurrentProvider,
@@ -96,6 +113,7 @@ func NewModelSettings(cfg *config.Config) ModelSettings {
 		modelList:    modelList,
 		config:       cfg,
 		showModels:   false,
+		pullInput:    newPullInput(),
 	}
 }
 
@@ -123,6 +141,14 @@ func fetchModels(provider, apiKey string) tea.Cmd {
 }
 
 func (m ModelSettings) Update(msg tea.Msg) (ModelSettings, tea.Cmd) {
+	if m.showModels && m.selectedProvider == "ollama" {
+		var cmd tea.Cmd
+		var handled bool
+		if m, cmd, handled = m.updateOllama(msg); handled {
+			return m, cmd
+		}
+	}
+
 	switch msg := msg.(type) {
 	case fetchModelsMsg:
 		if msg.err != nil {
@@ -168,7 +194,7 @@ func (m ModelSettings) Update(msg tea.Msg) (ModelSettings, tea.Cmd) {
 					// Return to settings menu and notify about model change
 					return m, func() tea.Msg {
 						return ModelChangeMsg{
-							Provider: m.config.CurrentProvider,
+							Provider: m.selectedProvider,
 							Model:    i.id,
 						}
 					}
@@ -183,7 +209,21 @@ func (m ModelSettings) Update(msg tea.Msg) (ModelSettings, tea.Cmd) {
 					}
 
 					// Show models list and fetch models
+					m.selectedProvider = i.provider
 					m.showModels = true
+					m.ollamaStatus = ""
+					m.modelList.AdditionalShortHelpKeys = nil
+					if i.provider == "ollama" {
+						m.modelList.AdditionalShortHelpKeys = func() []key.Binding {
+							return []key.Binding{
+								DefaultOllamaKeyMap.Pull,
+								DefaultOllamaKeyMap.Delete,
+								DefaultOllamaKeyMap.Details,
+								DefaultOllamaKeyMap.Running,
+							}
+						}
+					}
+					m.modelList.AdditionalFullHelpKeys = m.modelList.AdditionalShortHelpKeys
 					return m, fetchModels(i.provider, m.config.APIKeys[i.provider])
 				}
 			}
@@ -214,12 +254,21 @@ func (m ModelSettings) View() string {
 		Italic(true).
 		MarginTop(1)
 	var content string
-	if m.showModels {
+	if ollamaContent := m.ollamaView(); m.showModels && ollamaContent != "" {
+		content = ollamaContent
+	} else if m.showModels {
 		content = lipgloss.JoinVertical(
 			lipgloss.Left,
 			titleStyle.Render("Select Model"),
 			m.modelList.View(),
 		)
+		if m.ollamaStatus != "" {
+			content = lipgloss.JoinVertical(
+				lipgloss.Left,
+				content,
+				helpStyle.Render(m.ollamaStatus),
+			)
+		}
 	} else {
 		content = lipgloss.JoinVertical(
 			lipgloss.Center,
@@ -0,0 +1,384 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/tedfulk/goatmeal/services/providers/ollama"
+	"github.com/tedfulk/goatmeal/ui/theme"
+)
+
+// OllamaKeyMap defines the model management key bindings shown when the provider is Ollama
+type OllamaKeyMap struct {
+	Pull    key.Binding
+	Delete  key.Binding
+	Details key.Binding
+	Running key.Binding
+}
+
+var DefaultOllamaKeyMap = OllamaKeyMap{
+	Pull: key.NewBinding(
+		key.WithKeys("ctrl+p"),
+		key.WithHelp("ctrl+p", "pull model"),
+	),
+	Delete: key.NewBinding(
+		key.WithKeys("ctrl+d"),
+		key.WithHelp("ctrl+d", "delete model"),
+	),
+	Details: key.NewBinding(
+		key.WithKeys("ctrl+o"),
+		key.WithHelp("ctrl+o", "model details"),
+	),
+	Running: key.NewBinding(
+		key.WithKeys("ctrl+r"),
+		key.WithHelp("ctrl+r", "running models"),
+	),
+}
+
+// Messages for Ollama model management results
+// Pull updates carry the channel of their pull, so those of a cancelled pull are ignored
+type ollamaPullProgressMsg struct {
+	progress ollama.PullProgress
+	updates  chan tea.Msg
+}
+
+type ollamaPullDoneMsg struct {
+	model   string
+	err     error
+	updates chan tea.Msg
+}
+
+type ollamaDeleteMsg struct {
+	model string
+	err   error
+}
+
+type ollamaDetailsMsg struct {
+	model   string
+	details *ollama.ModelDetails
+	err     error
+}
+
+type ollamaRunningMsg struct {
+	models []ollama.RunningModel
+	err    error
+}
+
+// newPullInput creates the text input used to enter the name of a model to pull
+func newPullInput() textinput.Model {
+	ti := textinput.New()
+	ti.Placeholder = "Model name, e.g. llama3.2:3b"
+	ti.Width = 40
+	return ti
+}
+
+// sendLatest delivers msg on ch, replacing any update that hasn't been read yet
+// so the pull goroutine never blocks when the view stops listening
+func sendLatest(ch chan tea.Msg, msg tea.Msg) {
+	for {
+		select {
+		case ch <- msg:
+			return
+		default:
+			select {
+			case <-ch:
+			default:
+			}
+		}
+	}
+}
+
+// startOllamaPull starts pulling a model in the background and returns the channel its updates arrive on
+func startOllamaPull(ctx context.Context, model string) chan tea.Msg {
+	updates := make(chan tea.Msg, 1)
+	go func() {
+		provider := ollama.NewProvider("ollama")
+		err := provider.PullModel(ctx, model, func(progress ollama.PullProgress) {
+			sendLatest(updates, ollamaPullProgressMsg{progress: progress, updates: updates})
+		})
+		sendLatest(updates, ollamaPullDoneMsg{model: model, err: err, updates: updates})
+	}()
+	return updates
+}
+
+// waitForOllamaPull waits for the next update from a running pull
+func waitForOllamaPull(updates chan tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		return <-updates
+	}
+}
+
+func deleteOllamaModel(model string) tea.Cmd {
+	return func() tea.Msg {
+		err := ollama.NewProvider("ollama").DeleteModel(context.Background(), model)
+		return ollamaDeleteMsg{model: model, err: err}
+	}
+}
+
+func showOllamaModel(model string) tea.Cmd {
+	return func() tea.Msg {
+		details, err := ollama.NewProvider("ollama").ShowModel(context.Background(), model)
+		return ollamaDetailsMsg{model: model, details: details, err: err}
+	}
+}
+
+func listRunningOllamaModels() tea.Cmd {
+	return func() tea.Msg {
+		models, err := ollama.NewProvider("ollama").ListRunningModels(context.Background())
+		return ollamaRunningMsg{models: models, err: err}
+	}
+}
+
+// updateOllama handles Ollama model management messages and keys
+// It returns handled=false when the message should fall through to the model list
+func (m ModelSettings) updateOllama(msg tea.Msg) (ModelSettings, tea.Cmd, bool) {
+	switch msg := msg.(type) {
+	case ollamaPullProgressMsg:
+		if msg.updates != m.pullUpdates {
+			return m, nil, true
+		}
+		m.pullProgress = msg.progress
+		return m, waitForOllamaPull(m.pullUpdates), true
+
+	case ollamaPullDoneMsg:
+		if msg.updates != m.pullUpdates {
+			return m, nil, true
+		}
+		m.pulling = false
+		m.pullUpdates = nil
+		m.cancelPull = nil
+		if msg.err != nil {
+			m.ollamaStatus = fmt.Sprintf("Pull failed: %v", msg.err)
+			return m, nil, true
+		}
+		m.ollamaStatus = fmt.Sprintf("Pulled %s", msg.model)
+		m.ollamaMode = ""
+		return m, fetchModels("ollama", m.config.APIKeys["ollama"]), true
+
+	case ollamaDeleteMsg:
+		if msg.err != nil {
+			m.ollamaStatus = fmt.Sprintf("Delete failed: %v", msg.err)
+			return m, nil, true
+		}
+		m.ollamaStatus = fmt.Sprintf("Deleted %s", msg.model)
+		return m, fetchModels("ollama", m.config.APIKeys["ollama"]), true
+
+	case ollamaDetailsMsg:
+		if msg.err != nil {
+			m.ollamaStatus = fmt.Sprintf("Failed to load details: %v", msg.err)
+			return m, nil, true
+		}
+		m.ollamaMode = "details"
+		m.ollamaContent = formatOllamaDetails(msg.model, msg.details)
+		return m, nil, true
+
+	case ollamaRunningMsg:
+		if msg.err != nil {
+			m.ollamaStatus = fmt.Sprintf("Failed to list running models: %v", msg.err)
+			return m, nil, true
+		}
+		m.ollamaMode = "running"
+		m.ollamaContent = formatRunningModels(msg.models)
+		return m, nil, true
+
+	case tea.KeyMsg:
+		switch m.ollamaMode {
+		case "pull":
+			switch msg.String() {
+			case "esc":
+				if m.cancelPull != nil {
+					m.cancelPull()
+				}
+				m.pulling = false
+				m.pullUpdates = nil
+				m.cancelPull = nil
+				m.ollamaMode = ""
+				m.pullInput.Reset()
+				return m, nil, true
+			case "enter":
+				name := strings.TrimSpace(m.pullInput.Value())
+				if name == "" || m.pulling {
+					return m, nil, true
+				}
+				ctx, cancel := context.WithCancel(context.Background())
+				m.pulling = true
+				m.cancelPull = cancel
+				m.pullProgress = ollama.PullProgress{Status: "starting"}
+				m.ollamaStatus = ""
+				m.pullUpdates = startOllamaPull(ctx, name)
+				return m, waitForOllamaPull(m.pullUpdates), true
+			}
+			var cmd tea.Cmd
+			m.pullInput, cmd = m.pullInput.Update(msg)
+			return m, cmd, true
+
+		case "details", "running":
+			if msg.String() == "esc" || msg.String() == "q" {
+				m.ollamaMode = ""
+				m.ollamaContent = ""
+			}
+			return m, nil, true
+		}
+
+		// ctrl+d only deletes a model when pressed twice in a row on it
+		if !key.Matches(msg, DefaultOllamaKeyMap.Delete) && m.deleteID != "" {
+			m.deleteID = ""
+			m.ollamaStatus = ""
+		}
+
+		// Only handle management keys while browsing Ollama models and not filtering
+		if m.modelList.FilterState() == list.Filtering {
+			return m, nil, false
+		}
+		switch {
+		case key.Matches(msg, DefaultOllamaKeyMap.Pull):
+			m.ollamaMode = "pull"
+			m.ollamaStatus = ""
+			m.pullInput.Focus()
+			return m, textinput.Blink, true
+		case key.Matches(msg, DefaultOllamaKeyMap.Delete):
+			if i, ok := m.modelList.SelectedItem().(Model); ok {
+				if m.deleteID != i.id {
+					m.deleteID = i.id
+					m.ollamaStatus = fmt.Sprintf("ctrl+d again to delete %s", i.id)
+					return m, nil, true
+				}
+				m.deleteID = ""
+				m.ollamaStatus = fmt.Sprintf("Deleting %s...", i.id)
+				return m, deleteOllamaModel(i.id), true
+			}
+			return m, nil, true
+		case key.Matches(msg, DefaultOllamaKeyMap.Details):
+			if i, ok := m.modelList.SelectedItem().(Model); ok {
+				return m, showOllamaModel(i.id), true
+			}
+			return m, nil, true
+		case key.Matches(msg, DefaultOllamaKeyMap.Running):
+			return m, listRunningOllamaModels(), true
+		}
+	}
+
+	return m, nil, false
+}
+
+// ollamaView renders the Ollama management screens, or "" when the model list should be shown
+func (m ModelSettings) ollamaView() string {
+	titleStyle := theme.BaseStyle.Title.
+		Foreground(theme.CurrentTheme.Primary.GetColor())
+
+	helpStyle := lipgloss.NewStyle().
+		Foreground(theme.CurrentTheme.Secondary.GetColor())
+
+	switch m.ollamaMode {
+	case "pull":
+		lines := []string{
+			titleStyle.Render("Pull Ollama Model"),
+			"",
+			m.pullInput.View(),
+			"",
+		}
+		if m.pulling {
+			lines = append(lines,
+				renderProgressBar(m.pullProgress.Percent(), 40),
+				m.pullProgress.Status,
+			)
+		} else if m.ollamaStatus != "" {
+			lines = append(lines, m.ollamaStatus)
+		}
+		lines = append(lines, "", helpStyle.Render("enter: pull • esc: cancel"))
+		return lipgloss.JoinVertical(lipgloss.Left, lines...)
+
+	case "details", "running":
+		title := "Model Details"
+		if m.ollamaMode == "running" {
+			title = "Running Models"
+		}
+		return lipgloss.JoinVertical(
+			lipgloss.Left,
+			titleStyle.Render(title),
+			"",
+			m.ollamaContent,
+			"",
+			helpStyle.Render("esc: back"),
+		)
+	}
+
+	return ""
+}
+
+// renderProgressBar draws a simple text progress bar for a ratio between 0 and 1
+func renderProgressBar(ratio float64, width int) string {
+	if ratio < 0 {
+		ratio = 0
+	}
+	if ratio > 1 {
+		ratio = 1
+	}
+	filled := int(ratio * float64(width))
+
+	barStyle := lipgloss.NewStyle().
+		Foreground(theme.CurrentTheme.Primary.GetColor())
+
+	return barStyle.Render(strings.Repeat("█", filled)) +
+		strings.Repeat("░", width-filled) +
+		fmt.Sprintf(" %3.0f%%", ratio*100)
+}
+
+func formatOllamaDetails(model string, details *ollama.ModelDetails) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Model: %s\n", model))
+	sb.WriteString(fmt.Sprintf("Family: %s\n", details.Details.Family))
+	sb.WriteString(fmt.Sprintf("Format: %s\n", details.Details.Format))
+	sb.WriteString(fmt.Sprintf("Parameter size: %s\n", details.Details.ParameterSize))
+	sb.WriteString(fmt.Sprintf("Quantization: %s\n", details.Details.QuantizationLevel))
+
+	if details.Parameters != "" {
+		sb.WriteString("\nParameters:\n")
+		sb.WriteString(strings.TrimSpace(details.Parameters))
+		sb.WriteString("\n")
+	}
+	if details.Template != "" {
+		sb.WriteString("\nTemplate:\n")
+		sb.WriteString(strings.TrimSpace(details.Template))
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+func formatRunningModels(models []ollama.RunningModel) string {
+	if len(models) == 0 {
+		return "No models are loaded"
+	}
+
+	var sb strings.Builder
+	for _, model := range models {
+		sb.WriteString(fmt.Sprintf("%s  %s  %s  %s in VRAM  unloads %s\n",
+			model.Name,
+			model.Details.ParameterSize,
+			model.Details.QuantizationLevel,
+			formatBytes(model.SizeVRAM),
+			model.ExpiresAt.Format("15:04"),
+		))
+	}
+	return sb.String()
+}
+
+// formatBytes renders a byte count in human readable units
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
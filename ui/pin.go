@@ -0,0 +1,20 @@
+package ui
+
+import "fmt"
+
+// pinConversation pins or unpins the current conversation
+func (a *App) pinConversation(pinned bool) {
+	if a.currentConversationID == "" || len(a.messages) == 0 || a.messages[0].DBID == "" {
+		a.statusBar.SetError("Send a message before pinning the conversation")
+		return
+	}
+	if err := a.db.SetConversationPinned(a.currentConversationID, pinned); err != nil {
+		a.statusBar.SetError(fmt.Sprintf("Failed to pin conversation: %v", err))
+		return
+	}
+	if pinned {
+		a.statusBar.SetTemporaryText("📌 Pinned, this conversation is kept by cleanup")
+	} else {
+		a.statusBar.SetTemporaryText("Unpinned")
+	}
+}
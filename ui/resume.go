@@ -0,0 +1,72 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/tedfulk/goatmeal/database"
+	"github.com/tedfulk/goatmeal/services/search"
+)
+
+// OpenConversationMsg asks the app to continue a saved conversation in the chat view
+type OpenConversationMsg struct {
+	id           string
+	restoreModel bool // Switch to the provider and model the conversation was using
+}
+
+// openConversation loads a saved conversation into the chat so new turns are added to it
+func (a *App) openConversation(id string, restoreModel bool) error {
+	conv, err := a.db.ExportConversation(id, true)
+	if err != nil {
+		return err
+	}
+	summary, err := a.db.GetConversationSummary(id)
+	if err != nil {
+		return err
+	}
+
+	a.resetConversation()
+	a.currentConversationID = conv.ID
+	a.statusBar.SetConversationTitle(conv.Title)
+	a.loadBranch(conv, conv.Messages, conv.ActiveLeafID)
+	if summary != nil {
+		a.historySummary = summary.Summary
+		a.summarizedTurns = summary.SummarizedTurns
+	}
+
+	if restoreModel {
+		provider, model := conversationModel(database.ActiveBranch(conv.Messages, conv.ActiveLeafID), conv)
+		if provider == "" {
+			a.statusBar.SetTemporaryText("This conversation has no chat model to restore")
+		} else {
+			a.statusBar.UpdateProviderAndModel(provider, model)
+		}
+	}
+
+	a.updateConversationView()
+	return nil
+}
+
+// conversationModel returns the provider and model of the latest reply in branch
+// Search conversations without replies have no chat model
+func conversationModel(branch []database.Message, conv *database.Conversation) (string, string) {
+	for i := len(branch) - 1; i >= 0; i-- {
+		msg := branch[i]
+		if msg.Role == "assistant" && msg.Provider != "" && msg.Model != "" {
+			return msg.Provider, msg.Model
+		}
+	}
+	if conv.Provider == "" || search.IsBackend(conv.Provider) {
+		return "", ""
+	}
+	return conv.Provider, conv.Model
+}
+
+// handleOpenConversation switches to the chat view with the requested conversation
+func (a *App) handleOpenConversation(msg OpenConversationMsg) {
+	if err := a.openConversation(msg.id, msg.restoreModel); err != nil {
+		a.statusBar.SetError(fmt.Sprintf("Failed to open conversation: %v", err))
+		return
+	}
+	a.currentView = "chat"
+	a.showMenu = false
+}
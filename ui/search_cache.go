@@ -0,0 +1,66 @@
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/tedfulk/goatmeal/database"
+	"github.com/tedfulk/goatmeal/services/search"
+)
+
+// cachedSearch runs a search, reusing a cached response for the same query, options and backend
+// bypass skips the cache lookup but still stores the fresh response. cachedAt is zero for fresh results
+func (a *App) cachedSearch(backend search.Backend, query string, opts search.Options, bypass bool) (resp *search.SearchResponse, cachedAt time.Time, err error) {
+	ttl := search.CacheTTL(a.config.Settings.Search.CacheTTL)
+	if ttl == 0 {
+		resp, err = backend.Search(query, opts)
+		return resp, time.Time{}, err
+	}
+
+	normalizedQuery, domains, options := search.CacheKey(query, opts)
+
+	if !bypass {
+		entry, err := a.db.GetSearchCache(normalizedQuery, domains, backend.Name(), options, ttl)
+		if err != nil {
+			a.statusBar.SetError(fmt.Sprintf("Failed to read search cache: %v", err))
+		} else if entry != nil {
+			var cached search.SearchResponse
+			if err := json.Unmarshal([]byte(entry.Response), &cached); err == nil {
+				return &cached, entry.CreatedAt, nil
+			}
+		}
+	}
+
+	resp, err = backend.Search(query, opts)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return resp, time.Time{}, nil
+	}
+	entry := &database.SearchCacheEntry{
+		Query:     normalizedQuery,
+		Domains:   domains,
+		Backend:   backend.Name(),
+		Options:   options,
+		Response:  string(data),
+		CreatedAt: time.Now(),
+	}
+	if err := a.db.SaveSearchCache(entry); err != nil {
+		a.statusBar.SetError(fmt.Sprintf("Failed to cache search results: %v", err))
+	}
+
+	return resp, time.Time{}, nil
+}
+
+// searchSource returns the label shown on search results, noting when they came from the cache
+func searchSource(backendName string, cachedAt time.Time) string {
+	source := search.DisplayName(backendName)
+	if !cachedAt.IsZero() {
+		source += fmt.Sprintf(" (cached, %s)", search.FormatAge(cachedAt))
+	}
+	return source
+}
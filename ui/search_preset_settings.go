@@ -0,0 +1,350 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/tedfulk/goatmeal/config"
+	"github.com/tedfulk/goatmeal/ui/theme"
+)
+
+type SearchPresetItem struct {
+	name   string
+	preset config.SearchPreset
+}
+
+func (i SearchPresetItem) Title() string { return "@" + i.name }
+func (i SearchPresetItem) Description() string {
+	var parts []string
+	for _, domain := range i.preset.Domains {
+		parts = append(parts, "+"+domain)
+	}
+	for _, domain := range i.preset.ExcludeDomains {
+		parts = append(parts, "-"+domain)
+	}
+	if i.preset.Topic != "" {
+		parts = append(parts, "topic: "+i.preset.Topic)
+	}
+	return strings.Join(parts, " ")
+}
+func (i SearchPresetItem) FilterValue() string { return i.name }
+
+// SearchPresetKeyMap defines the key bindings of the search preset settings
+type SearchPresetKeyMap struct {
+	New    key.Binding
+	Edit   key.Binding
+	Delete key.Binding
+	Back   key.Binding
+}
+
+var DefaultSearchPresetKeyMap = SearchPresetKeyMap{
+	New: key.NewBinding(
+		key.WithKeys("n"),
+		key.WithHelp("n", "new preset"),
+	),
+	Edit: key.NewBinding(
+		key.WithKeys("enter", "e"),
+		key.WithHelp("enter", "edit preset"),
+	),
+	Delete: key.NewBinding(
+		key.WithKeys("ctrl+d"),
+		key.WithHelp("ctrl+d", "delete preset"),
+	),
+	Back: key.NewBinding(
+		key.WithKeys("esc"),
+		key.WithHelp("esc", "back"),
+	),
+}
+
+// Fields of the preset form
+const (
+	presetFieldName = iota
+	presetFieldDomains
+	presetFieldExclude
+	presetFieldTopic
+	presetFieldCount
+)
+
+// SearchPresetSettings manages the named domain presets used as @name in /web queries
+type SearchPresetSettings struct {
+	list    list.Model
+	config  *config.Config
+	editing bool
+	oldName string // Name of the preset being edited, empty for a new preset
+	inputs  []textinput.Model
+	focus   int
+	status  string
+	width   int
+	height  int
+}
+
+func NewSearchPresetSettings(cfg *config.Config) SearchPresetSettings {
+	l := list.New([]list.Item{}, list.NewDefaultDelegate(), 0, 0)
+	l.Title = ""
+	l.SetShowHelp(true)
+	l.SetFilteringEnabled(false)
+	l.AdditionalShortHelpKeys = func() []key.Binding {
+		return []key.Binding{
+			DefaultSearchPresetKeyMap.New,
+			DefaultSearchPresetKeyMap.Edit,
+			DefaultSearchPresetKeyMap.Delete,
+			DefaultSearchPresetKeyMap.Back,
+		}
+	}
+	l.AdditionalFullHelpKeys = l.AdditionalShortHelpKeys
+	l.Styles.Title = theme.BaseStyle.Title.
+		Foreground(theme.CurrentTheme.Primary.GetColor())
+
+	inputs := make([]textinput.Model, presetFieldCount)
+	placeholders := []string{
+		"Name, e.g. go",
+		"Domains, e.g. go.dev pkg.go.dev",
+		"Excluded domains, e.g. medium.com",
+		"Topic: general or news (optional)",
+	}
+	for i := range inputs {
+		inputs[i] = textinput.New()
+		inputs[i].Placeholder = placeholders[i]
+		inputs[i].Width = 50
+	}
+
+	s := SearchPresetSettings{
+		list:   l,
+		config: cfg,
+		inputs: inputs,
+	}
+	s.loadPresets()
+	return s
+}
+
+// loadPresets fills the list from the config, sorted by name
+func (s *SearchPresetSettings) loadPresets() {
+	names := make([]string, 0, len(s.config.SearchPresets))
+	for name := range s.config.SearchPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	items := make([]list.Item, len(names))
+	for i, name := range names {
+		items[i] = SearchPresetItem{name: name, preset: s.config.SearchPresets[name]}
+	}
+	s.list.SetItems(items)
+}
+
+func (s SearchPresetSettings) Update(msg tea.Msg) (SearchPresetSettings, tea.Cmd) {
+	if s.editing {
+		return s.updateForm(msg)
+	}
+
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch {
+		case key.Matches(msg, DefaultSearchPresetKeyMap.Back):
+			s.status = ""
+			return s, func() tea.Msg {
+				return SetViewMsg{view: "settings"}
+			}
+		case key.Matches(msg, DefaultSearchPresetKeyMap.New):
+			return s, s.startEditing("", config.SearchPreset{})
+		case key.Matches(msg, DefaultSearchPresetKeyMap.Edit):
+			if item, ok := s.list.SelectedItem().(SearchPresetItem); ok {
+				return s, s.startEditing(item.name, item.preset)
+			}
+			return s, nil
+		case key.Matches(msg, DefaultSearchPresetKeyMap.Delete):
+			if item, ok := s.list.SelectedItem().(SearchPresetItem); ok {
+				presets := s.copyPresets()
+				delete(presets, item.name)
+				if err := s.savePresets(presets); err != nil {
+					s.status = fmt.Sprintf("Failed to delete preset: %v", err)
+				} else {
+					s.status = fmt.Sprintf("Deleted @%s", item.name)
+				}
+			}
+			return s, nil
+		}
+	}
+
+	var cmd tea.Cmd
+	s.list, cmd = s.list.Update(msg)
+	return s, cmd
+}
+
+// startEditing opens the form for a preset, or a new preset when name is empty
+func (s *SearchPresetSettings) startEditing(name string, preset config.SearchPreset) tea.Cmd {
+	s.editing = true
+	s.oldName = name
+	s.status = ""
+	values := []string{
+		name,
+		strings.Join(preset.Domains, " "),
+		strings.Join(preset.ExcludeDomains, " "),
+		preset.Topic,
+	}
+	for i := range s.inputs {
+		s.inputs[i].SetValue(values[i])
+		s.inputs[i].Blur()
+	}
+	s.focus = presetFieldName
+	s.inputs[s.focus].Focus()
+	return textinput.Blink
+}
+
+func (s SearchPresetSettings) updateForm(msg tea.Msg) (SearchPresetSettings, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.String() {
+		case "esc":
+			s.editing = false
+			s.status = ""
+			return s, nil
+		case "tab", "down", "shift+tab", "up":
+			s.inputs[s.focus].Blur()
+			if msg.String() == "tab" || msg.String() == "down" {
+				s.focus = (s.focus + 1) % presetFieldCount
+			} else {
+				s.focus = (s.focus + presetFieldCount - 1) % presetFieldCount
+			}
+			s.inputs[s.focus].Focus()
+			return s, textinput.Blink
+		case "enter":
+			if err := s.saveForm(); err != nil {
+				s.status = err.Error()
+				return s, nil
+			}
+			s.editing = false
+			return s, nil
+		}
+	}
+
+	var cmd tea.Cmd
+	s.inputs[s.focus], cmd = s.inputs[s.focus].Update(msg)
+	return s, cmd
+}
+
+// saveForm validates the form and stores the preset
+func (s *SearchPresetSettings) saveForm() error {
+	name := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(s.inputs[presetFieldName].Value()), "@"))
+	if name == "" || strings.ContainsAny(name, " .@") {
+		return fmt.Errorf("Enter a name without spaces, dots or @")
+	}
+
+	preset := config.SearchPreset{
+		Domains:        presetDomains(s.inputs[presetFieldDomains].Value()),
+		ExcludeDomains: presetDomains(s.inputs[presetFieldExclude].Value()),
+		Topic:          strings.ToLower(strings.TrimSpace(s.inputs[presetFieldTopic].Value())),
+	}
+	if preset.Topic != "" && preset.Topic != "general" && preset.Topic != "news" {
+		return fmt.Errorf("Topic must be general or news")
+	}
+	if len(preset.Domains) == 0 && len(preset.ExcludeDomains) == 0 && preset.Topic == "" {
+		return fmt.Errorf("Add at least one domain, excluded domain or topic")
+	}
+
+	presets := s.copyPresets()
+	if _, exists := presets[name]; exists && name != s.oldName {
+		return fmt.Errorf("A preset named @%s already exists", name)
+	}
+	if s.oldName != "" {
+		delete(presets, s.oldName)
+	}
+	presets[name] = preset
+
+	if err := s.savePresets(presets); err != nil {
+		return fmt.Errorf("Failed to save preset: %v", err)
+	}
+	s.status = fmt.Sprintf("Saved @%s", name)
+	return nil
+}
+
+func (s *SearchPresetSettings) copyPresets() map[string]config.SearchPreset {
+	presets := make(map[string]config.SearchPreset, len(s.config.SearchPresets))
+	for name, preset := range s.config.SearchPresets {
+		presets[name] = preset
+	}
+	return presets
+}
+
+// savePresets writes the presets to the config file and refreshes the list
+func (s *SearchPresetSettings) savePresets(presets map[string]config.SearchPreset) error {
+	manager, err := config.NewManager()
+	if err != nil {
+		return err
+	}
+	if err := manager.SetSearchPresets(presets); err != nil {
+		return err
+	}
+	s.config.SearchPresets = presets
+	s.loadPresets()
+	return nil
+}
+
+// presetDomains splits a space or comma separated list of domains, ignoring +/- markers
+func presetDomains(value string) []string {
+	var domains []string
+	for _, domain := range strings.Fields(strings.ReplaceAll(value, ",", " ")) {
+		domain = strings.TrimLeft(domain, "+-")
+		if domain != "" {
+			domains = append(domains, domain)
+		}
+	}
+	return domains
+}
+
+func (s SearchPresetSettings) View() string {
+	menuStyle := theme.BaseStyle.Menu.
+		BorderForeground(theme.CurrentTheme.Primary.GetColor())
+
+	titleStyle := theme.BaseStyle.Title.
+		Foreground(theme.CurrentTheme.Primary.GetColor())
+
+	helpStyle := lipgloss.NewStyle().
+		Foreground(theme.CurrentTheme.Secondary.GetColor())
+
+	var menuContent string
+	if s.editing {
+		title := "New Search Preset"
+		if s.oldName != "" {
+			title = "Edit @" + s.oldName
+		}
+		labels := []string{"Name", "Domains", "Exclude domains", "Topic"}
+		lines := []string{titleStyle.Render(title), ""}
+		for i, input := range s.inputs {
+			lines = append(lines, labels[i]+":", input.View(), "")
+		}
+		if s.status != "" {
+			lines = append(lines, s.status, "")
+		}
+		lines = append(lines, helpStyle.Render("tab: next field • enter: save • esc: cancel"))
+		menuContent = lipgloss.JoinVertical(lipgloss.Left, lines...)
+	} else {
+		lines := []string{titleStyle.Render("Search Presets")}
+		if len(s.list.Items()) == 0 {
+			lines = append(lines, "", "No presets yet. Press n to add one, then use it as /web query @name.")
+		}
+		lines = append(lines, s.list.View())
+		if s.status != "" {
+			lines = append(lines, helpStyle.Render(s.status))
+		}
+		menuContent = lipgloss.JoinVertical(lipgloss.Left, lines...)
+	}
+
+	return lipgloss.Place(
+		s.width,
+		s.height,
+		lipgloss.Center,
+		lipgloss.Center,
+		menuStyle.Render(menuContent),
+	)
+}
+
+func (s *SearchPresetSettings) SetSize(width, height int) {
+	s.width = width
+	s.height = height
+	s.list.SetSize(width-4, height-12)
+}
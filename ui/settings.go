@@ -36,6 +36,8 @@ func NewSettingsMenu(cfg *config.Config) SettingsMenu {
 		SettingsMenuItem{title: "Theme", description: "Change application theme"},
 		SettingsMenuItem{title: "Glamour", description: "Configure markdown formatting"},
 			SettingsMenuItem{title: "Username", description: "Change your username"},
+		SettingsMenuItem{title: "Search Presets", description: "Manage domain presets for /web @name"},
+		SettingsMenuItem{title: "Export Directory", description: "Choose where conversations are exported"},
 	}
 
 	l := list.New(items, list.NewDefaultDelegate(), 0, 0)
@@ -94,6 +96,10 @@ func (s SettingsMenu) Update(msg tea.Msg) (SettingsMenu, tea.Cmd) {
 					s.currentView = "username"
 				case "Change Model":
 					s.currentView = "model"
+				case "Search Presets":
+					s.currentView = "searchpresets"
+				case "Export Directory":
+					s.currentView = "exportdir"
 				}
 				return s, nil
 			case "esc":
@@ -36,6 +36,7 @@ type StatusBar struct {
 	errorMessage      string
 	errorTimer        *time.Timer
 	isEnhancedSearch bool
+	historyNotice     string
 }
 
 // NewStatusBar creates a new status bar
@@ -129,6 +130,8 @@ func (s *StatusBar) View() string {
 			searchIndicator = "🔍+"
 		}
 		rightContent = searchIndicator
+	} else if s.historyNotice != "" {
+		rightContent = s.historyNotice
 	}
 
 	leftSection := lipgloss.JoinHorizontal(
@@ -186,6 +189,11 @@ func (s *StatusBar) SetEnhancedSearch(enabled bool) {
 	s.isEnhancedSearch = enabled
 }
 
+// SetHistoryNotice shows that older turns were left out of the prompt, an empty notice clears it
+func (s *StatusBar) SetHistoryNotice(notice string) {
+	s.historyNotice = notice
+}
+
 func (s *StatusBar) UpdateProviderAndModel(provider, model string) {
 	s.config.CurrentProvider = provider
 	s.config.CurrentModel = model
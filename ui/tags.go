@@ -0,0 +1,75 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tedfulk/goatmeal/database"
+)
+
+// tagCommand handles /tag: without arguments it shows the conversation's tags,
+// names add tags and names starting with - remove them, e.g. /tag go -draft
+func (a *App) tagCommand(args string) {
+	if a.currentConversationID == "" || len(a.messages) == 0 || a.messages[0].DBID == "" {
+		a.statusBar.SetError("Send a message before tagging the conversation")
+		return
+	}
+
+	var add, remove []string
+	for _, field := range strings.Fields(args) {
+		if name, ok := strings.CutPrefix(field, "-"); ok {
+			remove = append(remove, database.ParseTags(name)...)
+		} else {
+			add = append(add, database.ParseTags(field)...)
+		}
+	}
+	if len(add) > 0 {
+		if err := a.db.AddConversationTags(a.currentConversationID, add...); err != nil {
+			a.statusBar.SetError(fmt.Sprintf("Failed to add tags: %v", err))
+			return
+		}
+	}
+	if len(remove) > 0 {
+		if err := a.db.RemoveConversationTags(a.currentConversationID, remove...); err != nil {
+			a.statusBar.SetError(fmt.Sprintf("Failed to remove tags: %v", err))
+			return
+		}
+	}
+
+	tags, err := a.db.GetConversationTags(a.currentConversationID)
+	if err != nil {
+		a.statusBar.SetError(fmt.Sprintf("Failed to load tags: %v", err))
+		return
+	}
+	if len(tags) == 0 {
+		a.statusBar.SetTemporaryText("🏷 No tags, add one with /tag name")
+		return
+	}
+	a.statusBar.SetTemporaryText("🏷 " + formatTags(tags))
+}
+
+// applyAutoTags tags a new conversation by the auto-tag rules matching the current system prompt and provider
+func (a *App) applyAutoTags(provider string) {
+	systemPrompt := a.currentProvenance().SystemPrompt
+	var tags []string
+	for _, rule := range a.config.Settings.AutoTags {
+		if rule.Matches(systemPrompt, provider) {
+			tags = append(tags, rule.Tags...)
+		}
+	}
+	if len(tags) == 0 {
+		return
+	}
+	if err := a.db.AddConversationTags(a.currentConversationID, tags...); err != nil {
+		a.statusBar.SetError(fmt.Sprintf("Failed to tag conversation: %v", err))
+	}
+}
+
+// formatTags renders tag names as #name, separated by spaces
+func formatTags(tags []string) string {
+	formatted := make([]string, len(tags))
+	for i, tag := range tags {
+		formatted[i] = "#" + tag
+	}
+	return strings.Join(formatted, " ")
+}
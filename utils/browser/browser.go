@@ -0,0 +1,66 @@
+package browser
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Open opens url in the user's web browser
+// $BROWSER takes precedence. Like other tools it may list several commands separated
+// by colons, and a %s in a command is replaced by the URL
+func Open(url string) error {
+	if env := os.Getenv("BROWSER"); env != "" {
+		var lastErr error
+		for _, command := range strings.Split(env, ":") {
+			if command = strings.TrimSpace(command); command == "" {
+				continue
+			}
+			if lastErr = start(browserCommand(command, url)); lastErr == nil {
+				return nil
+			}
+		}
+		return fmt.Errorf("error opening browser from $BROWSER: %w", lastErr)
+	}
+
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	if err := start(cmd); err != nil {
+		return fmt.Errorf("error opening browser: %w", err)
+	}
+	return nil
+}
+
+// browserCommand builds the command for a $BROWSER entry
+func browserCommand(command, url string) *exec.Cmd {
+	args := strings.Fields(command)
+	replaced := false
+	for i, arg := range args {
+		if strings.Contains(arg, "%s") {
+			args[i] = strings.ReplaceAll(arg, "%s", url)
+			replaced = true
+		}
+	}
+	if !replaced {
+		args = append(args, url)
+	}
+	return exec.Command(args[0], args[1:]...)
+}
+
+// start runs the browser without waiting for it, so terminal browsers don't take over the UI
+func start(cmd *exec.Cmd) error {
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go cmd.Wait()
+	return nil
+}
@@ -5,9 +5,22 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 )
 
+// Location modes
+const (
+	ModeManual = "manual" // Use the configured city, region, country and timezone
+	ModeAuto   = "auto"   // Detect the location from the IP address, cached for detectMaxAge
+	ModeOff    = "off"    // Only send the local time
+)
+
+// detectMaxAge is how long an auto-detected location is reused before detecting it again
+const detectMaxAge = 24 * time.Hour
+
 type IPInfo struct {
 	IP       string `json:"ip"`
 	City     string `json:"city"`
@@ -16,8 +29,15 @@ type IPInfo struct {
 	Timezone string `json:"timezone"`
 }
 
+// cachedInfo is the auto-detected location stored on disk
+type cachedInfo struct {
+	Info       IPInfo    `json:"info"`
+	DetectedAt time.Time `json:"detected_at"`
+}
+
 func GetLocationInfo() (*IPInfo, error) {
-	resp, err := http.Get("https://ipapi.co/json/")
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get("https://ipapi.co/json/")
 	if err != nil {
 		return nil, err
 	}
@@ -36,18 +56,124 @@ func GetLocationInfo() (*IPInfo, error) {
 	return &info, nil
 }
 
-func GetFormattedLocationAndTime() string {
+// Resolver provides the location context sent with enhanced searches
+type Resolver struct {
+	mode      string
+	manual    IPInfo
+	cachePath string
+}
+
+// NewResolver creates a resolver for the given mode
+// An empty mode is manual when any location field is set and off otherwise.
+// cachePath is where auto-detected locations are stored
+func NewResolver(mode string, manual IPInfo, cachePath string) *Resolver {
+	mode = strings.ToLower(strings.TrimSpace(mode))
+	if mode == "" {
+		mode = ModeOff
+		if manual.City != "" || manual.Region != "" || manual.Country != "" || manual.Timezone != "" {
+			mode = ModeManual
+		}
+	}
+	return &Resolver{mode: mode, manual: manual, cachePath: cachePath}
+}
+
+// Info returns the location for the current mode, or nil when the location is off or unknown
+func (r *Resolver) Info() *IPInfo {
+	switch r.mode {
+	case ModeManual:
+		info := r.manual
+		return &info
+	case ModeAuto:
+		info, err := r.detect()
+		if err != nil {
+			return nil
+		}
+		return info
+	default:
+		return nil
+	}
+}
+
+// FormattedLocationAndTime returns the location and current time for use in prompts
+func (r *Resolver) FormattedLocationAndTime() string {
+	return FormatLocationAndTime(r.Info(), time.Now())
+}
+
+// detect returns the cached auto-detected location, detecting it again when the cache is stale
+func (r *Resolver) detect() (*IPInfo, error) {
+	if cached, err := r.readCache(); err == nil && time.Since(cached.DetectedAt) < detectMaxAge {
+		return &cached.Info, nil
+	}
+
 	info, err := GetLocationInfo()
 	if err != nil {
-		return fmt.Sprintf("Unknown location, Current time: %s", time.Now().Format("2006-01-02 15:04:05"))
+		// Fall back to a stale cached location rather than none at all
+		if cached, cacheErr := r.readCache(); cacheErr == nil {
+			return &cached.Info, nil
+		}
+		return nil, err
 	}
 
-	loc, err := time.LoadLocation(info.Timezone)
+	// The IP address isn't needed for the prompt, don't keep it on disk
+	info.IP = ""
+	r.writeCache(cachedInfo{Info: *info, DetectedAt: time.Now()})
+	return info, nil
+}
+
+func (r *Resolver) readCache() (*cachedInfo, error) {
+	if r.cachePath == "" {
+		return nil, fmt.Errorf("no location cache")
+	}
+	data, err := os.ReadFile(r.cachePath)
 	if err != nil {
-		loc = time.Local
+		return nil, err
 	}
+	var cached cachedInfo
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return nil, err
+	}
+	return &cached, nil
+}
 
-	return fmt.Sprintf("Location: %s, %s, %s, Current time: %s", 
-		info.City, info.Region, info.Country, 
-		time.Now().In(loc).Format("2006-01-02 15:04:05"))
-} 
\ No newline at end of file
+func (r *Resolver) writeCache(cached cachedInfo) {
+	if r.cachePath == "" {
+		return
+	}
+	data, err := json.Marshal(cached)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(r.cachePath), 0755); err != nil {
+		return
+	}
+	os.WriteFile(r.cachePath, data, 0600)
+}
+
+// FormatLocationAndTime describes a location and the time there
+// A nil info only includes the local time
+func FormatLocationAndTime(info *IPInfo, now time.Time) string {
+	if info == nil {
+		return fmt.Sprintf("Current time: %s", now.Format("2006-01-02 15:04:05"))
+	}
+
+	loc := time.Local
+	if info.Timezone != "" {
+		if tz, err := time.LoadLocation(info.Timezone); err == nil {
+			loc = tz
+		}
+	}
+
+	var parts []string
+	for _, part := range []string{info.City, info.Region, info.Country} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 0 {
+		return fmt.Sprintf("Current time: %s", now.In(loc).Format("2006-01-02 15:04:05"))
+	}
+
+	return fmt.Sprintf("Location: %s, Current time: %s",
+		strings.Join(parts, ", "),
+		now.In(loc).Format("2006-01-02 15:04:05"))
+}
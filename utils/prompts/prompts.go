@@ -57,6 +57,36 @@ Examples of titles:
 	Now, refine the following prompt:
 
 	%s`
+
+	summarizeHistorySystemPrompt = `You summarize chat transcripts so the conversation can continue without the full history. Preserve facts, decisions, names, code identifiers, open questions and the user's preferences. Write plain prose without markdown headers, and do not add anything that was not said.`
+
+	summarizeHistoryPrompt = `Previous summary (may be empty):
+%s
+
+New conversation turns to fold into the summary:
+%s
+
+Write an updated summary of the whole conversation so far in under 300 words.`
+
+	groundedAnswerPrompt = `Answer the question using the numbered web search results below. Follow these rules:
+
+1. Base the answer on the search results. If they don't contain the answer, say so.
+2. Cite sources inline with their number in square brackets, e.g. [1] or [2][3], right after the statement they support.
+3. Only cite numbers that appear in the search results.
+4. Do not add a list of sources at the end, it is added automatically.
+
+Search results:
+
+%s
+Question: %s`
+
+	pageQuestionPrompt = `Answer the question using the web page below. Base the answer on the page content. If the page doesn't contain the answer, say so.
+
+Page: %s
+
+%s
+
+Question: %s`
 )
 
 // GetEnhanceSearchPrompt returns the formatted enhance search prompt
@@ -77,3 +107,23 @@ func GetTitleSystemPrompt() string {
 func GetEnhanceProgrammingPrompt(prompt string) string {
 	return fmt.Sprintf(enhanceProgrammingPrompt, prompt)
 }
+
+// GetSummarizeHistorySystemPrompt returns the system prompt used when summarizing conversation history
+func GetSummarizeHistorySystemPrompt() string {
+	return summarizeHistorySystemPrompt
+}
+
+// GetSummarizeHistoryPrompt returns the formatted prompt for folding turns into a rolling summary
+func GetSummarizeHistoryPrompt(previousSummary, turns string) string {
+	return fmt.Sprintf(summarizeHistoryPrompt, previousSummary, turns)
+}
+
+// GetPageQuestionPrompt returns the prompt asking the model to answer a question about a fetched page
+func GetPageQuestionPrompt(pageURL, content, question string) string {
+	return fmt.Sprintf(pageQuestionPrompt, pageURL, content, question)
+}
+
+// GetGroundedAnswerPrompt returns the prompt asking the model to answer from numbered search results
+func GetGroundedAnswerPrompt(sources, question string) string {
+	return fmt.Sprintf(groundedAnswerPrompt, sources, question)
+}